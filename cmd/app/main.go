@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 	dbadapter "virast/internal/adapters/database"
 	"virast/internal/adapters/httpapi"
 	redisadapter "virast/internal/adapters/redis"
@@ -13,6 +15,7 @@ import (
 	"virast/internal/core/fanoutqueue"
 	"virast/internal/core/follower"
 	followerapp "virast/internal/core/follower/service"
+	notificationapp "virast/internal/core/notification/service"
 	"virast/internal/core/post"
 	postapp "virast/internal/core/post/service"
 	"virast/internal/core/timeline"
@@ -24,10 +27,25 @@ import (
 
 func main() {
 	config.Init() // بارگذاری تنظیمات از .env
-	
+
 	// اتصال به دیتابیس و اجرای مایگریشن‌ها
 	config.InitDB()
 
+	// حذف ردیف‌های تکراری timeline قبل از ساخت unique index
+	if err := dbadapter.RemoveDuplicateTimelines(); err != nil {
+		log.Fatal("Error removing duplicate timelines:", err)
+	}
+
+	// ستون follower_count در اولین اجرا اضافه می‌شود و باید از روی جدول followers پر شود
+	recountFollowers := !config.DB.Migrator().HasColumn(&user.User{}, "FollowerCount")
+
+	// حذف follow های تکراری قبل از ساخت unique index؛ شمارنده‌ها بعد از آن دوباره شمرده می‌شوند
+	removedFollows, err := dbadapter.RemoveDuplicateFollowers()
+	if err != nil {
+		log.Fatal("Error removing duplicate followers:", err)
+	}
+	recountFollowers = recountFollowers || removedFollows > 0
+
 	// اعمال مایگریشن برای مدل‌ها
 	if err := config.DB.AutoMigrate(
 		&user.User{},
@@ -35,10 +53,22 @@ func main() {
 		&follower.Follower{},
 		&timeline.Timeline{},
 		&fanoutqueue.FanoutQueue{},
+		&post.PostRevision{},
 	); err != nil {
 		log.Fatal("Error during migrations:", err)
 	}
 
+	if recountFollowers {
+		if err := dbadapter.RecountFollowers(); err != nil {
+			log.Fatal("Error recounting followers:", err)
+		}
+	}
+
+	// هر کاربر حداکثر یک repost فعال از هر پست
+	if err := dbadapter.EnsureActiveRepostIndex(); err != nil {
+		log.Fatal("Error creating repost unique index:", err)
+	}
+
 	log.Println("✅ Database migrations completed")
 
 	// اتصال به Redis
@@ -50,17 +80,59 @@ func main() {
 	// چاپ پیغام قبل از راه‌اندازی سرور
 	log.Println("App is running...")
 
-	userRepo := dbadapter.NewUserRepositoryDatabase()                                                // آداپتر خروجی
-	postRepo := dbadapter.NewPostRepositoryDatabase()                                                // آداپتر خروجی
-	fanoutRedis := redisadapter.NewFanoutRepositoryRedis(config.RedisClient)                         // آداپتر خروجی
-	fanoutRepo := dbadapter.NewFanoutRepositoryDatabase()                                            // آداپتر خروجی
-	followerRepo := dbadapter.NewFollowerRepositoryDatabase()                                        // آداپتر خروجی
-	timelineRepo := dbadapter.NewtimelineRepositoryDatabase()                                        // آداپتر خروجی
-	userSvc := userapp.NewUserService(userRepo, []byte(os.Getenv("JWT_SECRET")))                     // یوزکیس/سرویس
-	postSvc := postapp.NewPostService(postRepo, fanoutRepo, fanoutRedis, followerRepo, timelineRepo) // یوزکیس/سرویس
-	followerScv := followerapp.NewFollowerService(followerRepo)                                      // یوزکیس/سرویس
-	timelineScv := timelineapp.NewTimelineService(timelineRepo)                                      // یوزکیس/سرویس
-	r := httpapi.SetupRoutes(userSvc, postSvc, followerScv, timelineScv)                             // تزریق یوزکیس به آداپتر ورودی
+	celebrityThreshold, err := strconv.ParseInt(os.Getenv("CELEBRITY_THRESHOLD"), 10, 64) // بیشتر از این تعداد follower: pull به جای push
+	if err != nil || celebrityThreshold < 0 {
+		celebrityThreshold = 10000 // مقدار پیش‌فرض
+	}
+
+	timelineMaxLen, err := strconv.ParseInt(os.Getenv("TIMELINE_MAX_LEN"), 10, 64) // حداکثر طول timeline ZSET هر کاربر
+	if err != nil || timelineMaxLen < 0 {
+		timelineMaxLen = 800 // مقدار پیش‌فرض
+	}
+
+	breakerFailures, err := strconv.Atoi(os.Getenv("REDIS_BREAKER_FAILURES")) // تعداد خطای پشت‌سرهم تا باز شدن مدار
+	if err != nil || breakerFailures <= 0 {
+		breakerFailures = 5 // مقدار پیش‌فرض
+	}
+
+	breakerOpenTimeout, err := time.ParseDuration(os.Getenv("REDIS_BREAKER_OPEN_TIMEOUT")) // مدت باز ماندن مدار قبل از half-open
+	if err != nil || breakerOpenTimeout <= 0 {
+		breakerOpenTimeout = 10 * time.Second // مقدار پیش‌فرض
+	}
+	redisBreaker := redisadapter.NewCircuitBreaker(breakerFailures, breakerOpenTimeout)
+
+	cacheTTL, err := time.ParseDuration(os.Getenv("CACHE_TTL")) // مدت نگهداری پست و کاربر در cache
+	if err != nil || cacheTTL <= 0 {
+		cacheTTL = time.Hour // مقدار پیش‌فرض
+	}
+
+	postEditWindow, err := time.ParseDuration(os.Getenv("POST_EDIT_WINDOW")) // مهلت ویرایش پست بعد از انتشار
+	if err != nil || postEditWindow <= 0 {
+		postEditWindow = 15 * time.Minute // مقدار پیش‌فرض
+	}
+
+	var wsAllowedOrigins []string // origin هایی که اجازه‌ی اتصال WebSocket دارند؛ خالی یعنی فقط همان host
+	for _, o := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			wsAllowedOrigins = append(wsAllowedOrigins, o)
+		}
+	}
+
+	userRepo := redisadapter.NewUserRepositoryCache(dbadapter.NewUserRepositoryDatabase(), config.RedisClient, cacheTTL, redisBreaker)                                      // آداپتر خروجی
+	postRepo := redisadapter.NewPostRepositoryCache(dbadapter.NewPostRepositoryDatabase(), config.RedisClient, cacheTTL, redisBreaker)                                      // آداپتر خروجی
+	fanoutRedis := redisadapter.NewFanoutRepositoryRedis(config.RedisClient, timelineMaxLen, redisBreaker)                                                                  // آداپتر خروجی
+	fanoutQueue := redisadapter.NewFanoutQueueRedis(config.RedisClient)                                                                                                     // آداپتر خروجی
+	fanoutRepo := dbadapter.NewFanoutRepositoryDatabase()                                                                                                                   // آداپتر خروجی
+	followerRepo := dbadapter.NewFollowerRepositoryDatabase()                                                                                                               // آداپتر خروجی
+	timelineRepo := dbadapter.NewtimelineRepositoryDatabase(postRepo, userRepo, celebrityThreshold, timelineMaxLen, redisBreaker)                                           // آداپتر خروجی
+	eventHub := redisadapter.NewEventHubRedis(config.RedisClient, redisBreaker)                                                                                             // آداپتر خروجی
+	unitOfWork := dbadapter.NewUnitOfWorkDatabase()                                                                                                                         // آداپتر خروجی
+	userSvc := userapp.NewUserService(userRepo, []byte(os.Getenv("JWT_SECRET")))                                                                                            // یوزکیس/سرویس
+	postSvc := postapp.NewPostService(postRepo, fanoutRepo, fanoutQueue, fanoutRedis, followerRepo, timelineRepo, unitOfWork, postRepo, postEditWindow, userRepo, eventHub) // یوزکیس/سرویس
+	followerScv := followerapp.NewFollowerService(followerRepo, eventHub, fanoutRepo, fanoutQueue, unitOfWork)                                                              // یوزکیس/سرویس
+	timelineScv := timelineapp.NewTimelineService(timelineRepo, eventHub)                                                                                                   // یوزکیس/سرویس
+	notificationSvc := notificationapp.NewNotificationService(eventHub)                                                                                                     // یوزکیس/سرویس
+	r := httpapi.SetupRoutes(userSvc, postSvc, followerScv, timelineScv, notificationSvc, wsAllowedOrigins)                                                                 // تزریق یوزکیس به آداپتر ورودی
 	// -------------------------------------------
 
 	batchSizeStr := os.Getenv("BATCH_SIZE") // تعداد رکوردهای batch برای Redis و timeline
@@ -68,17 +140,81 @@ func main() {
 	if err != nil || batchSize <= 0 {
 		batchSize = 100 // مقدار پیش‌فرض
 	}
-	fanoutWorker := workers.NewFanoutWorker(fanoutRepo, fanoutRedis, followerRepo, timelineRepo, batchSize)
+	claimIdleStr := os.Getenv("FANOUT_CLAIM_IDLE") // مدت بی‌پاسخ ماندن پیام قبل از برداشتن توسط worker دیگر
+	claimIdle, err := time.ParseDuration(claimIdleStr)
+	if err != nil || claimIdle <= 0 {
+		claimIdle = 30 * time.Second // مقدار پیش‌فرض
+	}
+
+	leaseTTLStr := os.Getenv("FANOUT_LEASE_TTL") // مدت اعتبار lease یک worker روی رکورد fanout_queue
+	leaseTTL, err := time.ParseDuration(leaseTTLStr)
+	if err != nil || leaseTTL <= 0 {
+		leaseTTL = time.Minute // مقدار پیش‌فرض
+	}
+
+	maxAttempts, err := strconv.Atoi(os.Getenv("FANOUT_MAX_ATTEMPTS")) // تعداد تلاش قبل از failed شدن رکورد
+	if err != nil || maxAttempts <= 0 {
+		maxAttempts = 5 // مقدار پیش‌فرض
+	}
+
+	retryBase, err := time.ParseDuration(os.Getenv("FANOUT_RETRY_BASE")) // فاصله‌ی پایه‌ی backoff نمایی
+	if err != nil || retryBase <= 0 {
+		retryBase = 5 * time.Second // مقدار پیش‌فرض
+	}
+
+	backfillLimit, err := strconv.Atoi(os.Getenv("BACKFILL_LIMIT")) // تعداد پست‌های قبلی followee که بعد از follow به تایم‌لاین اضافه می‌شوند
+	if err != nil || backfillLimit < 0 {
+		backfillLimit = 20 // مقدار پیش‌فرض
+	}
+
+	replyFanout := os.Getenv("REPLY_FANOUT") // مخاطبان پاسخ‌ها: mutual، all یا none
+	switch replyFanout {
+	case workers.ReplyFanoutMutual, workers.ReplyFanoutAll, workers.ReplyFanoutNone:
+	default:
+		replyFanout = workers.ReplyFanoutMutual // مقدار پیش‌فرض
+	}
+
+	hostname, _ := os.Hostname()
+	workerID := fmt.Sprintf("%s-%d", hostname, os.Getpid()) // نام یکتای consumer در consumer group
+	fanoutWorker, err := workers.NewFanoutWorker(workers.FanoutWorkerConfig{
+		FanoutRepo:         fanoutRepo,
+		Queue:              fanoutQueue,
+		FanoutRedis:        fanoutRedis,
+		PostRepo:           postRepo,
+		FollowerRepo:       followerRepo,
+		TimelineRepo:       timelineRepo,
+		UserRepo:           userRepo,
+		Events:             eventHub,
+		BatchSize:          batchSize,
+		WorkerID:           workerID,
+		ClaimIdle:          claimIdle,
+		LeaseTTL:           leaseTTL,
+		MaxAttempts:        maxAttempts,
+		RetryBase:          retryBase,
+		CelebrityThreshold: celebrityThreshold,
+		BackfillLimit:      backfillLimit,
+		ReplyFanout:        replyFanout,
+	})
+	if err != nil {
+		log.Fatal("Error creating fanout worker:", err)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if err := fanoutQueue.EnsureGroup(ctx); err != nil {
+		log.Fatal("Error creating fanout consumer group:", err)
+	}
+
 	// TEST
 	testStability(ctx, userSvc, postSvc, followerScv)
 	// End TEST
 
-	// اجرای worker در پس‌زمینه
+	// اجرای worker و relay در پس‌زمینه
 	go fanoutWorker.Run(ctx)
+	go workers.NewOutboxRelay(fanoutRepo, fanoutQueue).Run(ctx)
+	go eventHub.Run(ctx)
+	go logCacheStats(ctx, postRepo, userRepo)
 
 	// اجرای سرور Gin (در اینجا سرور به صورت بلوکینگ عمل می‌کند)
 	if err := r.Run(":" + os.Getenv("APP_PORT")); err != nil {
@@ -86,6 +222,23 @@ func main() {
 	}
 }
 
+// logCacheStats چاپ دوره‌ای hit/miss cache پست‌ها و کاربران
+func logCacheStats(ctx context.Context, posts *redisadapter.PostRepositoryCache, users *redisadapter.UserRepositoryCache) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			postHits, postMisses := posts.Stats()
+			userHits, userMisses := users.Stats()
+			log.Printf("📊 Cache stats: posts hit=%d miss=%d, users hit=%d miss=%d\n", postHits, postMisses, userHits, userMisses)
+		}
+	}
+}
+
 // closeResources بستن اتصالات به Redis و دیتابیس
 func closeResources() {
 	// بستن اتصال به Redis
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"time"
+	dbadapter "virast/internal/adapters/database"
+	redisadapter "virast/internal/adapters/redis"
+	"virast/internal/config"
+	timelineapp "virast/internal/core/timeline/service"
+)
+
+// warmtimelines بازسازی timeline ZSET کاربران فعال از جدول timeline بعد از ری‌استارت یا flush شدن Redis
+//
+//	go run ./cmd/warmtimelines -days 30
+//	go run ./cmd/warmtimelines -rescore   # یک‌بار بعد از امتیاز میلی‌ثانیه‌ای
+func main() {
+	days := flag.Int("days", 30, "warm users who received timeline entries in the last N days")
+	batch := flag.Int("batch", 500, "number of users loaded per page")
+	rescore := flag.Bool("rescore", false, "first rebuild every timeline ZSET that still holds second-based scores")
+	flag.Parse()
+
+	config.Init()
+	config.InitDB()
+	config.InitRedis()
+	defer config.RedisClient.Close()
+
+	timelineMaxLen, err := strconv.ParseInt(os.Getenv("TIMELINE_MAX_LEN"), 10, 64)
+	if err != nil || timelineMaxLen < 0 {
+		timelineMaxLen = 800 // مقدار پیش‌فرض
+	}
+
+	timelineRepo := dbadapter.NewtimelineRepositoryDatabase(
+		dbadapter.NewPostRepositoryDatabase(),
+		dbadapter.NewUserRepositoryDatabase(),
+		0,
+		timelineMaxLen,
+		redisadapter.NewCircuitBreaker(5, 10*time.Second),
+	)
+	timelineSvc := timelineapp.NewTimelineService(timelineRepo, nil) // بدون stream
+
+	// یک‌بار بعد از تغییر امتیاز تایم‌لاین از ثانیه به میلی‌ثانیه
+	if *rescore {
+		start := time.Now()
+		users, entries, err := timelineSvc.RescoreLegacyTimelines(context.Background(), *batch)
+		if err != nil {
+			log.Fatal("Error rescoring timelines: ", err)
+		}
+		log.Printf("✅ Rescored %d legacy timelines (%d entries) in %s\n", users, entries, time.Since(start))
+	}
+
+	since := time.Now().AddDate(0, 0, -*days)
+	start := time.Now()
+	users, entries, err := timelineSvc.WarmTimelines(context.Background(), since, *batch)
+	if err != nil {
+		log.Fatal("Error warming timelines: ", err)
+	}
+	log.Printf("✅ Warmed %d timelines (%d entries) in %s\n", users, entries, time.Since(start))
+}
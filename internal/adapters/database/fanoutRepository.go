@@ -2,42 +2,255 @@ package database
 
 import (
 	"context"
-	"github.com/gofrs/uuid"
+	"time"
 	"virast/internal/config"
 	"virast/internal/core/fanoutqueue"
+	fanoutPort "virast/internal/ports/fanoutqueue"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type FanoutRepositoryDatabase struct{}
+type FanoutRepositoryDatabase struct {
+	tx *gorm.DB // در صورت اجرا داخل UnitOfWork
+}
 
 func NewFanoutRepositoryDatabase() *FanoutRepositoryDatabase {
 	return &FanoutRepositoryDatabase{}
 }
 
+func (repo *FanoutRepositoryDatabase) db() *gorm.DB {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return config.DB
+}
+
 func (repo *FanoutRepositoryDatabase) Create(ctx context.Context, fanout *fanoutqueue.FanoutQueue) (*fanoutqueue.FanoutQueue, error) {
-	if err := config.DB.Create(fanout).Error; err != nil {
+	if err := repo.db().Create(fanout).Error; err != nil {
 		return nil, err
 	}
 	return fanout, nil
 }
 
-func (repo *FanoutRepositoryDatabase) GetPendingPosts(ctx context.Context, limit int64) ([]*fanoutqueue.FanoutQueue, error) {
+func (repo *FanoutRepositoryDatabase) GetByID(ctx context.Context, id uuid.UUID) (*fanoutqueue.FanoutQueue, error) {
+	var fanout fanoutqueue.FanoutQueue
+	if err := repo.db().Where("id = ?", id).First(&fanout).Error; err != nil {
+		return nil, err
+	}
+	return &fanout, nil
+}
+
+// ClaimPending گرفتن حداکثر limit رکورد pending که زمان تلاش‌شان رسیده برای workerID با lease به مدت leaseTTL؛
+// رکوردهایی که worker دیگری قفل کرده با SKIP LOCKED رد می‌شوند
+func (repo *FanoutRepositoryDatabase) ClaimPending(ctx context.Context, workerID string, limit int, leaseTTL time.Duration) ([]*fanoutqueue.FanoutQueue, error) {
 	var fanouts []*fanoutqueue.FanoutQueue
-	if err := config.DB.
-		//Preload("Post"). // بارگذاری relation با Post
-		//Preload("User"). // بارگذاری relation با User
-		Where("status = ?", "pending").
-		Limit(int(limit)).
+	err := repo.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", fanoutqueue.StatusPending).
+			Where("next_attempt_at IS NULL OR next_attempt_at <= ?", time.Now()).
+			Order("created_at").
+			Limit(limit).
+			Find(&fanouts).Error; err != nil {
+			return err
+		}
+		if len(fanouts) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, 0, len(fanouts))
+		for _, fq := range fanouts {
+			ids = append(ids, fq.ID)
+		}
+
+		expiresAt := time.Now().Add(leaseTTL)
+		if err := tx.Model(&fanoutqueue.FanoutQueue{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":           fanoutqueue.StatusProcessing,
+				"claimed_by":       workerID,
+				"lease_expires_at": expiresAt,
+			}).Error; err != nil {
+			return err
+		}
+
+		for _, fq := range fanouts {
+			fq.Status = fanoutqueue.StatusProcessing
+			fq.ClaimedBy = workerID
+			fq.LeaseExpiresAt = &expiresAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fanouts, nil
+}
+
+// ClaimByID گرفتن یک رکورد مشخص در صورتی که هنوز pending باشد و در backoff نباشد؛
+// اگر رکورد در اختیار worker دیگری یا انجام‌شده باشد nil برمی‌گرداند
+func (repo *FanoutRepositoryDatabase) ClaimByID(ctx context.Context, id uuid.UUID, workerID string, leaseTTL time.Duration) (*fanoutqueue.FanoutQueue, error) {
+	res := repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id = ? AND status = ?", id, fanoutqueue.StatusPending).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", time.Now()).
+		Updates(map[string]interface{}{
+			"status":           fanoutqueue.StatusProcessing,
+			"claimed_by":       workerID,
+			"lease_expires_at": time.Now().Add(leaseTTL),
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return repo.GetByID(ctx, id)
+}
+
+// SaveCheckpoint ذخیره‌ی آخرین follower تحویل‌شده و تمدید lease رکوردی که در اختیار workerID است
+func (repo *FanoutRepositoryDatabase) SaveCheckpoint(ctx context.Context, id uuid.UUID, workerID, lastFollowerID string, leaseTTL time.Duration) error {
+	res := repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, fanoutqueue.StatusProcessing, workerID).
+		Updates(map[string]interface{}{
+			"last_follower_id": lastFollowerID,
+			"lease_expires_at": time.Now().Add(leaseTTL),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fanoutPort.ErrLeaseLost
+	}
+	return nil
+}
+
+// ReclaimExpired برگرداندن رکوردهای processing با lease منقضی‌شده به pending؛ lease منقضی‌شده یک تلاش
+// ناموفق حساب می‌شود تا رکوردی که worker را از کار می‌اندازد بی‌نهایت برداشته نشود و بعد از maxAttempts failed شود
+func (repo *FanoutRepositoryDatabase) ReclaimExpired(ctx context.Context, maxAttempts int) (int64, error) {
+	var reclaimed int64
+	err := repo.db().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		expired := tx.Model(&fanoutqueue.FanoutQueue{}).
+			Where("status = ? AND lease_expires_at < ?", fanoutqueue.StatusProcessing, now).
+			Session(&gorm.Session{})
+
+		res := expired.
+			Where("attempts + 1 >= ?", maxAttempts).
+			Updates(map[string]interface{}{
+				"status":           fanoutqueue.StatusFailed,
+				"attempts":         gorm.Expr("attempts + 1"),
+				"last_error":       "lease expired",
+				"processed_at":     now,
+				"claimed_by":       "",
+				"lease_expires_at": nil,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		reclaimed = res.RowsAffected
+
+		res = expired.
+			Updates(map[string]interface{}{
+				"status":           fanoutqueue.StatusPending,
+				"attempts":         gorm.Expr("attempts + 1"),
+				"last_error":       "lease expired",
+				"claimed_by":       "",
+				"lease_expires_at": nil,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		reclaimed += res.RowsAffected
+		return nil
+	})
+	return reclaimed, err
+}
+
+// MarkDone پایان موفق رکوردی که در اختیار workerID است؛ اگر lease از دست رفته باشد ErrLeaseLost برمی‌گرداند
+func (repo *FanoutRepositoryDatabase) MarkDone(ctx context.Context, id uuid.UUID, workerID string) error {
+	return leaseResult(repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, fanoutqueue.StatusProcessing, workerID).
+		Updates(map[string]interface{}{
+			"status":           fanoutqueue.StatusDone,
+			"processed_at":     time.Now(),
+			"lease_expires_at": nil,
+		}))
+}
+
+// GetUnpublished رکوردهای outbox که هنوز در stream منتشر نشده‌اند و قبل از olderThan ساخته شده‌اند
+func (repo *FanoutRepositoryDatabase) GetUnpublished(ctx context.Context, olderThan time.Time, limit int) ([]*fanoutqueue.FanoutQueue, error) {
+	var fanouts []*fanoutqueue.FanoutQueue
+	if err := repo.db().
+		Where("published_at IS NULL AND status = ? AND created_at < ?", fanoutqueue.StatusPending, olderThan).
+		Order("created_at").
+		Limit(limit).
 		Find(&fanouts).Error; err != nil {
 		return nil, err
 	}
 	return fanouts, nil
 }
 
-func (repo *FanoutRepositoryDatabase) MarkDone(ctx context.Context, id uuid.UUID) error {
-	if err := config.DB.Model(&fanoutqueue.FanoutQueue{}).
-		Where("id = ?", id).
-		Update("status", "done").Error; err != nil {
-		return err
+// MarkPublished ثبت زمان انتشار رکوردها در stream
+func (repo *FanoutRepositoryDatabase) MarkPublished(ctx context.Context, ids ...uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id IN ?", ids).
+		Update("published_at", time.Now()).Error
+}
+
+// MarkFailed انتقال رکوردی که در اختیار workerID است به وضعیت نهایی failed (dead-letter)
+func (repo *FanoutRepositoryDatabase) MarkFailed(ctx context.Context, id uuid.UUID, workerID, lastErr string) error {
+	return leaseResult(repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, fanoutqueue.StatusProcessing, workerID).
+		Updates(map[string]interface{}{
+			"status":           fanoutqueue.StatusFailed,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_error":       lastErr,
+			"processed_at":     time.Now(),
+			"claimed_by":       "",
+			"lease_expires_at": nil,
+		}))
+}
+
+// Retry برگرداندن رکوردی که در اختیار workerID است به pending تا بعد از nextAttemptAt دوباره برداشته شود
+func (repo *FanoutRepositoryDatabase) Retry(ctx context.Context, id uuid.UUID, workerID string, nextAttemptAt time.Time, lastErr string) error {
+	return leaseResult(repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, fanoutqueue.StatusProcessing, workerID).
+		Updates(map[string]interface{}{
+			"status":           fanoutqueue.StatusPending,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"next_attempt_at":  nextAttemptAt,
+			"last_error":       lastErr,
+			"claimed_by":       "",
+			"lease_expires_at": nil,
+		}))
+}
+
+// Release برگرداندن رکوردی که در اختیار workerID است به pending بدون شمردن تلاش ناموفق
+// (مثلاً وقتی Redis قطع است)؛ checkpoint حفظ می‌شود تا fanout از همان نقطه ادامه پیدا کند
+func (repo *FanoutRepositoryDatabase) Release(ctx context.Context, id uuid.UUID, workerID, lastErr string) error {
+	return leaseResult(repo.db().Model(&fanoutqueue.FanoutQueue{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, fanoutqueue.StatusProcessing, workerID).
+		Updates(map[string]interface{}{
+			"status":           fanoutqueue.StatusPending,
+			"last_error":       lastErr,
+			"claimed_by":       "",
+			"lease_expires_at": nil,
+		}))
+}
+
+// leaseResult تبدیل نتیجه‌ی update شرطی روی lease به خطا؛ اگر ردیفی تغییر نکرده باشد رکورد
+// دیگر processing و در اختیار این worker نیست
+func leaseResult(res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fanoutPort.ErrLeaseLost
 	}
 	return nil
 }
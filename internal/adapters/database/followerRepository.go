@@ -2,43 +2,135 @@ package database
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"virast/internal/config"
 	"virast/internal/core/follower"
+	"virast/internal/core/user"
+	followerPort "virast/internal/ports/follower"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// followIndex unique index روی (user_id, follower_id)؛ index غیریکتای قبلی legacyFollowIndex نام داشت
+const (
+	followIndex       = "idx_followers_user_follower_unique"
+	legacyFollowIndex = "idx_followers_user_follower"
 )
 
 // FollowerRepositoryDatabase پیاده‌سازی FollowerRepository برای دیتابیس
-type FollowerRepositoryDatabase struct{}
+type FollowerRepositoryDatabase struct {
+	tx *gorm.DB // در صورت اجرا داخل UnitOfWork
+}
 
 // NewFollowerRepositoryDatabase سازنده FollowerRepositoryDatabase
 func NewFollowerRepositoryDatabase() *FollowerRepositoryDatabase {
 	return &FollowerRepositoryDatabase{}
 }
 
+func (repo *FollowerRepositoryDatabase) db() *gorm.DB {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return config.DB
+}
+
+// FollowUser ثبت follow و افزایش follower_count کاربر دنبال‌شده؛ برای یکی ماندن این دو باید داخل UnitOfWork اجرا شود.
+// follow تکراری (نقض followIndex) با ErrAlreadyFollowing گزارش می‌شود و شمارنده تغییر نمی‌کند
 func (repo *FollowerRepositoryDatabase) FollowUser(ctx context.Context, follower *follower.Follower) (*follower.Follower, error) {
-	if err := config.DB.Create(follower).Error; err != nil {
+	if err := repo.db().Create(follower).Error; err != nil {
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && myErr.Number == mysqlDuplicateEntry && strings.Contains(myErr.Message, followIndex) {
+			return nil, followerPort.ErrAlreadyFollowing
+		}
+		return nil, err
+	}
+	if err := repo.addFollowerCount(follower.UserID.String(), 1); err != nil {
 		return nil, err
 	}
 	return follower, nil
 }
 
+// UnfollowUser حذف follow و کم کردن follower_count به اندازه‌ی ردیف‌های حذف‌شده؛
+// اگر ردیفی حذف نشود ErrNotFollowing برمی‌گرداند
 func (repo *FollowerRepositoryDatabase) UnfollowUser(ctx context.Context, followerID, followeeID string) error {
-	if err := config.DB.Where("follower_id = ? AND user_id = ?", followerID, followeeID).Delete(&follower.Follower{}).Error; err != nil {
-		return err
+	res := repo.db().Where("follower_id = ? AND user_id = ?", followerID, followeeID).Delete(&follower.Follower{})
+	if res.Error != nil {
+		return res.Error
 	}
-	return nil
+	if res.RowsAffected == 0 {
+		return followerPort.ErrNotFollowing
+	}
+	return repo.addFollowerCount(followeeID, -res.RowsAffected)
+}
+
+func (repo *FollowerRepositoryDatabase) addFollowerCount(userID string, delta int64) error {
+	return repo.db().Model(&user.User{}).
+		Where("id = ?", userID).
+		Update("follower_count", gorm.Expr("GREATEST(follower_count + ?, 0)", delta)).Error
+}
+
+// RemoveDuplicateFollowers حذف follow های تکراری (به جز قدیمی‌ترین) و index غیریکتای قبلی پیش از ساخت followIndex
+// در مایگریشن؛ تعداد ردیف‌های حذف‌شده برگردانده می‌شود تا follower_count دوباره شمرده شود
+func RemoveDuplicateFollowers() (int64, error) {
+	migrator := config.DB.Migrator()
+	if !migrator.HasTable(&follower.Follower{}) || migrator.HasIndex(&follower.Follower{}, followIndex) {
+		return 0, nil
+	}
+	res := config.DB.Exec(`DELETE f1 FROM followers f1
+		JOIN followers f2 ON f1.user_id = f2.user_id AND f1.follower_id = f2.follower_id
+			AND (f1.created_at > f2.created_at OR (f1.created_at = f2.created_at AND f1.id > f2.id))`)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if migrator.HasIndex(&follower.Follower{}, legacyFollowIndex) {
+		if err := migrator.DropIndex(&follower.Follower{}, legacyFollowIndex); err != nil {
+			return 0, err
+		}
+	}
+	return res.RowsAffected, nil
+}
+
+// RecountFollowers پر کردن follower_count همه‌ی کاربران از روی جدول followers (بعد از اضافه شدن ستون در مایگریشن)
+func RecountFollowers() error {
+	return config.DB.Exec(`UPDATE users SET follower_count =
+		(SELECT COUNT(*) FROM followers WHERE followers.user_id = users.id)`).Error
 }
 
 func (repo *FollowerRepositoryDatabase) GetFollowersByUserID(ctx context.Context, userID string) ([]*follower.Follower, error) {
 	var followers []*follower.Follower
-	if err := config.DB.Where("user_id = ?", userID).Find(&followers).Error; err != nil {
+	if err := repo.db().Where("user_id = ?", userID).Find(&followers).Error; err != nil {
 		return nil, err
 	}
 	return followers, nil
 }
 
+// GetFollowersPage صفحه‌ای از followers به ترتیب follower_id بعد از afterFollowerID (keyset pagination)
+func (repo *FollowerRepositoryDatabase) GetFollowersPage(ctx context.Context, userID, afterFollowerID string, limit int) ([]*follower.Follower, error) {
+	var followers []*follower.Follower
+	q := repo.db().Where("user_id = ?", userID)
+	if afterFollowerID != "" {
+		q = q.Where("follower_id > ?", afterFollowerID)
+	}
+	if err := q.Order("follower_id").Limit(limit).Find(&followers).Error; err != nil {
+		return nil, err
+	}
+	return followers, nil
+}
+
+// CountFollowers تعداد followers از ستون follower_count کاربر
+func (repo *FollowerRepositoryDatabase) CountFollowers(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := repo.db().Model(&user.User{}).Where("id = ?", userID).Pluck("follower_count", &count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (repo *FollowerRepositoryDatabase) GetFollowingByUserID(ctx context.Context, followerID string) ([]*follower.Follower, error) {
 	var following []*follower.Follower
-	if err := config.DB.Where("follower_id = ?", followerID).Find(&following).Error; err != nil {
+	if err := repo.db().Where("follower_id = ?", followerID).Find(&following).Error; err != nil {
 		return nil, err
 	}
 	return following, nil
@@ -46,8 +138,22 @@ func (repo *FollowerRepositoryDatabase) GetFollowingByUserID(ctx context.Context
 
 func (repo *FollowerRepositoryDatabase) IsFollowing(ctx context.Context, followerID, followeeID string) (bool, error) {
 	var count int64
-	if err := config.DB.Model(&follower.Follower{}).Where("follower_id = ? AND user_id = ?", followerID, followeeID).Count(&count).Error; err != nil {
+	if err := repo.db().Model(&follower.Follower{}).Where("follower_id = ? AND user_id = ?", followerID, followeeID).Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
+
+// FilterFollowing آن‌هایی از followerIDs که followeeID را دنبال می‌کنند، با یک کوئری
+func (repo *FollowerRepositoryDatabase) FilterFollowing(ctx context.Context, followerIDs []string, followeeID string) ([]string, error) {
+	var ids []string
+	if len(followerIDs) == 0 {
+		return ids, nil
+	}
+	if err := repo.db().Model(&follower.Follower{}).
+		Where("user_id = ? AND follower_id IN ?", followeeID, followerIDs).
+		Pluck("follower_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
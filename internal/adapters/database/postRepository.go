@@ -1,37 +1,213 @@
 package database
 
 import (
+	"errors"
+	"strings"
+	"time"
 	"virast/internal/config"
 	"virast/internal/core/post"
+	"virast/internal/core/timeline"
+	postPort "virast/internal/ports/post"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// activeRepostIndex جلوگیری از دو repost فعال یک پست توسط یک کاربر؛ روی ستون مجازی active_repost_of_id
+// ساخته می‌شود که فقط برای repost های حذف‌نشده مقدار دارد تا بعد از حذف repost بتوان دوباره repost کرد
+const activeRepostIndex = "idx_posts_user_active_repost"
+
+// mysqlDuplicateEntry کد خطای MySQL برای نقض unique index
+const mysqlDuplicateEntry = 1062
+
 // PostRepositoryDatabase پیاده‌سازی PostRepository برای دیتابیس
-type PostRepositoryDatabase struct{}
+type PostRepositoryDatabase struct {
+	tx *gorm.DB // در صورت اجرا داخل UnitOfWork
+}
 
 // NewPostRepositoryDatabase سازنده PostRepositoryDatabase
 func NewPostRepositoryDatabase() *PostRepositoryDatabase {
 	return &PostRepositoryDatabase{}
 }
 
+func (repo *PostRepositoryDatabase) db() *gorm.DB {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return config.DB
+}
+
+// Create ثبت پست؛ repost تکراری (نقض activeRepostIndex) با ErrAlreadyReposted گزارش می‌شود
 func (repo *PostRepositoryDatabase) Create(post *post.Post) (*post.Post, error) {
-	if err := config.DB.Create(post).Error; err != nil {
+	if err := repo.db().Create(post).Error; err != nil {
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && myErr.Number == mysqlDuplicateEntry && strings.Contains(myErr.Message, activeRepostIndex) {
+			return nil, postPort.ErrAlreadyReposted
+		}
 		return nil, err
 	}
 	return post, nil
 }
 
+// EnsureActiveRepostIndex ساخت ستون مجازی active_repost_of_id و unique index روی (user_id, active_repost_of_id)
+// در مایگریشن؛ repost های تکراری قبلی (به جز قدیمی‌ترین) پیش از ساخت index حذف نرم می‌شوند
+func EnsureActiveRepostIndex() error {
+	if config.DB.Migrator().HasIndex(&post.Post{}, activeRepostIndex) {
+		return nil
+	}
+	if err := config.DB.Exec(`UPDATE posts p1
+		JOIN posts p2 ON p1.user_id = p2.user_id AND p1.repost_of_id = p2.repost_of_id
+			AND p2.deleted_at IS NULL AND (p1.created_at > p2.created_at OR (p1.created_at = p2.created_at AND p1.id > p2.id))
+		SET p1.deleted_at = NOW(3)
+		WHERE p1.deleted_at IS NULL`).Error; err != nil {
+		return err
+	}
+	if !config.DB.Migrator().HasColumn(&post.Post{}, "active_repost_of_id") {
+		if err := config.DB.Exec(`ALTER TABLE posts ADD COLUMN active_repost_of_id char(36)
+			AS (IF(deleted_at IS NULL, repost_of_id, NULL)) VIRTUAL`).Error; err != nil {
+			return err
+		}
+	}
+	return config.DB.Exec("CREATE UNIQUE INDEX " + activeRepostIndex + " ON posts (user_id, active_repost_of_id)").Error
+}
+
+// FindByID بازیابی پست با شناسه؛ پست حذف‌شده هم (با DeletedAt) برگردانده می‌شود
 func (repo *PostRepositoryDatabase) FindByID(id string) (*post.Post, error) {
 	var post post.Post
-	if err := config.DB.Where("id = ?", id).First(&post).Error; err != nil {
+	if err := repo.db().Where("id = ?", id).First(&post).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, postPort.ErrPostNotFound
+		}
 		return nil, err
 	}
 	return &post, nil
 }
 
-func (repo *PostRepositoryDatabase) FindByUserID(userID string) ([]*post.Post, error) {
+// FindByIDForUpdate مثل FindByID ولی ردیف پست را تا پایان تراکنش قفل می‌کند (SELECT ... FOR UPDATE)؛
+// فقط داخل UnitOfWork معنی دارد
+func (repo *PostRepositoryDatabase) FindByIDForUpdate(id string) (*post.Post, error) {
+	var post post.Post
+	if err := repo.db().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&post).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, postPort.ErrPostNotFound
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+// FindByIDs بازیابی دسته‌ای پست‌ها با یک کوئری؛ ترتیب خروجی تضمین نمی‌شود
+func (repo *PostRepositoryDatabase) FindByIDs(ids []string) ([]*post.Post, error) {
+	var posts []*post.Post
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	if err := repo.db().Where("id IN ? AND deleted_at IS NULL", ids).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// FindByUserID پست‌های حذف‌نشده‌ی کاربر از جدید به قدیم؛ اگر limit مثبت باشد فقط limit پست آخر
+func (repo *PostRepositoryDatabase) FindByUserID(userID string, limit int) ([]*post.Post, error) {
+	var posts []*post.Post
+	q := repo.db().Where("user_id = ? AND deleted_at IS NULL", userID).Order("created_at DESC, id DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	if err := q.Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// FindPageByUserID limit پست حذف‌نشده‌ی کاربر بعد از cursor، از جدید به قدیم (keyset روی created_at و id)
+func (repo *PostRepositoryDatabase) FindPageByUserID(userID string, after *timeline.Cursor, limit int) ([]*post.Post, error) {
+	var posts []*post.Post
+	q := repo.db().Where("user_id = ? AND deleted_at IS NULL", userID)
+	if after != nil {
+		afterTime := time.UnixMilli(int64(after.Score))
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", afterTime, afterTime, after.PostID)
+	}
+	if err := q.Order("created_at DESC, id DESC").Limit(limit).Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// FindReplies limit پاسخ مستقیم حذف‌نشده‌ی parentID بعد از cursor، از قدیم به جدید
+func (repo *PostRepositoryDatabase) FindReplies(parentID string, after *timeline.Cursor, limit int) ([]*post.Post, error) {
 	var posts []*post.Post
-	if err := config.DB.Where("user_id = ?", userID).Find(&posts).Error; err != nil {
+	q := repo.db().Where("reply_to_id = ? AND deleted_at IS NULL", parentID)
+	if after != nil {
+		afterTime := time.UnixMilli(int64(after.Score))
+		q = q.Where("created_at > ? OR (created_at = ? AND id > ?)", afterTime, afterTime, after.PostID)
+	}
+	if err := q.Order("created_at ASC, id ASC").Limit(limit).Find(&posts).Error; err != nil {
 		return nil, err
 	}
 	return posts, nil
-}
\ No newline at end of file
+}
+
+// AddReplyCount تغییر اتمیک تعداد پاسخ‌ها؛ UpdateColumn تا updated_at پست تغییر نکند
+func (repo *PostRepositoryDatabase) AddReplyCount(id string, delta int) error {
+	return repo.db().Model(&post.Post{}).
+		Where("id = ?", id).
+		UpdateColumn("reply_count", gorm.Expr("GREATEST(reply_count + ?, 0)", delta)).Error
+}
+
+// FindRepost repost حذف‌نشده‌ی userID از پست postID
+func (repo *PostRepositoryDatabase) FindRepost(userID, postID string) (*post.Post, error) {
+	var p post.Post
+	err := repo.db().Where("user_id = ? AND repost_of_id = ? AND deleted_at IS NULL", userID, postID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, postPort.ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Delete حذف نرم پست؛ اگر پست قبلاً حذف شده باشد ErrPostNotFound برمی‌گرداند
+func (repo *PostRepositoryDatabase) Delete(id string) error {
+	res := repo.db().Model(&post.Post{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Update("deleted_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return postPort.ErrPostNotFound
+	}
+	return nil
+}
+
+// Update ذخیره‌ی محتوای ویرایش‌شده؛ اگر پست حذف شده باشد ErrPostNotFound برمی‌گرداند
+func (repo *PostRepositoryDatabase) Update(p *post.Post) error {
+	res := repo.db().Model(&post.Post{}).
+		Where("id = ? AND deleted_at IS NULL", p.ID).
+		Updates(map[string]interface{}{"content": p.Content, "edited_at": p.EditedAt})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return postPort.ErrPostNotFound
+	}
+	return nil
+}
+
+// AddRevision ذخیره‌ی نسخه‌ی قبلی پست
+func (repo *PostRepositoryDatabase) AddRevision(rev *post.PostRevision) error {
+	return repo.db().Create(rev).Error
+}
+
+// FindRevisions نسخه‌های قبلی پست از جدید به قدیم
+func (repo *PostRepositoryDatabase) FindRevisions(postID string) ([]*post.PostRevision, error) {
+	var revs []*post.PostRevision
+	if err := repo.db().Where("post_id = ?", postID).Order("created_at DESC").Find(&revs).Error; err != nil {
+		return nil, err
+	}
+	return revs, nil
+}
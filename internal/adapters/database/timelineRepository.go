@@ -3,23 +3,77 @@ package database
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	redisadapter "virast/internal/adapters/redis"
 	"virast/internal/config"
 	postEntity "virast/internal/core/post"
 	timelineEntity "virast/internal/core/timeline"
 	postPort "virast/internal/ports/post"
+	timelinePort "virast/internal/ports/timeline"
 	userPort "virast/internal/ports/user"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type TimelineRepositoryDatabase struct{}
+type TimelineRepositoryDatabase struct {
+	tx *gorm.DB // در صورت اجرا داخل UnitOfWork
 
-func NewtimelineRepositoryDatabase() *TimelineRepositoryDatabase {
-	return &TimelineRepositoryDatabase{}
+	Posts postPort.PostRepository // برای hydrate کردن پست‌های تایم‌لاین
+	Users userPort.UserRepository // برای hydrate کردن نویسندگان
+
+	// CelebrityThreshold نویسندگانی با followers بیشتر از این مقدار push نمی‌شوند و پست‌هایشان موقع خواندن pull می‌شود
+	CelebrityThreshold int64
+	// MaxLen حداکثر طول timeline ZSET؛ بازسازی ZSET از MySQL به همین تعداد محدود است
+	MaxLen int64
+	// Breaker در زمان قطعی Redis تایم‌لاین مستقیماً از MySQL خوانده می‌شود
+	Breaker *redisadapter.CircuitBreaker
+}
+
+const (
+	defaultRebuildLen = 800              // تعداد پست‌های بازسازی‌شده وقتی MaxLen بدون محدودیت است
+	rebuildLockTTL    = 30 * time.Second // قفل بازسازی هر کاربر تا چند درخواست همزمان یک ZSET را نسازند
+	redisRangeCap     = 1024             // حداکثر ظرفیت اولیه‌ی نتیجه‌ی خواندن از ZSET
+	pageRefillRounds  = 3                // دفعات خواندن ادامه‌ی تایم‌لاین وقتی repost های تکراری صفحه را کوتاه کرده‌اند
+)
+
+// legacyScoreMax بازه‌ی امتیازهای ثانیه‌ای قدیمی در دستورات ZSET (انحصاری)
+var legacyScoreMax = "(" + strconv.FormatFloat(timelineEntity.LegacyScoreLimit, 'f', -1, 64)
+
+func NewtimelineRepositoryDatabase(
+	posts postPort.PostRepository,
+	users userPort.UserRepository,
+	celebrityThreshold, maxLen int64,
+	breaker *redisadapter.CircuitBreaker,
+) *TimelineRepositoryDatabase {
+	return &TimelineRepositoryDatabase{
+		Posts:              posts,
+		Users:              users,
+		CelebrityThreshold: celebrityThreshold,
+		MaxLen:             maxLen,
+		Breaker:            breaker,
+	}
 }
 
-// Add اضافه کردن یک پست به جدول timeline
+func (repo *TimelineRepositoryDatabase) db() *gorm.DB {
+	if repo.tx != nil {
+		return repo.tx
+	}
+	return config.DB
+}
+
+// timelineEntry یک پست در تایم‌لاین به همراه امتیاز مرتب‌سازی آن
+type timelineEntry struct {
+	PostID string
+	Score  float64
+}
+
+// Add اضافه کردن یک پست به جدول timeline؛ اگر (user_id, post_id) قبلاً وجود داشته باشد کاری انجام نمی‌شود
 func (repo *TimelineRepositoryDatabase) Add(ctx context.Context, tl *timelineEntity.Timeline) error {
 	if tl.ID == uuid.Nil {
 		tl.ID = uuid.Must(uuid.NewV4())
@@ -28,14 +82,15 @@ func (repo *TimelineRepositoryDatabase) Add(ctx context.Context, tl *timelineEnt
 		tl.CreatedAt = time.Now()
 	}
 
-	if err := config.DB.Create(tl).Error; err != nil {
+	if err := repo.db().Clauses(clause.OnConflict{DoNothing: true}).Create(tl).Error; err != nil {
 		fmt.Println("Error adding to timeline:", err)
 		return err
 	}
 	return nil
 }
 
-// AddBatch اضافه کردن چندین پست به جدول timeline به صورت دسته‌ای
+// AddBatch اضافه کردن چندین پست به جدول timeline به صورت دسته‌ای؛
+// ردیف‌های تکراری (تحویل دوباره‌ی همان fanout) نادیده گرفته می‌شوند
 func (repo *TimelineRepositoryDatabase) AddBatch(ctx context.Context, timelines []*timelineEntity.Timeline) error {
 	if len(timelines) == 0 {
 		fmt.Println("⚠️ No timelines to add")
@@ -60,7 +115,7 @@ func (repo *TimelineRepositoryDatabase) AddBatch(ctx context.Context, timelines
 	}
 
 	// insert batch
-	if err := config.DB.CreateInBatches(&timelines, len(timelines)).Error; err != nil {
+	if err := repo.db().Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&timelines, len(timelines)).Error; err != nil {
 		return fmt.Errorf("error adding batch to timeline: %w", err)
 	}
 
@@ -68,38 +123,632 @@ func (repo *TimelineRepositoryDatabase) AddBatch(ctx context.Context, timelines
 	return nil
 }
 
-// GetTimelineByUserID بازیابی تایم‌لاین کاربر با start و limit
+// RemoveDuplicateTimelines حذف ردیف‌های تکراری (user_id, post_id) قبل از ساخت unique index در مایگریشن
+func RemoveDuplicateTimelines() error {
+	if !config.DB.Migrator().HasTable(&timelineEntity.Timeline{}) {
+		return nil
+	}
+	return config.DB.Exec(`DELETE t1 FROM timelines t1
+		JOIN timelines t2 ON t1.user_id = t2.user_id AND t1.post_id = t2.post_id AND t1.id > t2.id`).Error
+}
+
+// GetTimelineByUserID بازیابی تایم‌لاین کاربر با start و limit (صفحه‌بندی قدیمی بر اساس offset)
 func (repo *TimelineRepositoryDatabase) GetTimelineByUserID(ctx context.Context, userID string, start, limit int64) ([]*postPort.PostDTO, error) {
+	if limit <= 0 || start < 0 {
+		return []*postPort.PostDTO{}, nil
+	}
+
+	// برای صفحه‌ی [start, start+limit) از ابتدای تایم‌لاین start+limit آیتم لازم است
+	entries, err := repo.timelineEntries(ctx, userID, nil, start+limit)
+	if err != nil {
+		return nil, err
+	}
+	if start >= int64(len(entries)) {
+		return []*postPort.PostDTO{}, nil
+	}
+
+	return repo.hydrate(ctx, userID, entries[start:], nil)
+}
+
+// GetTimelinePage بازیابی limit پست بعد از cursor (یا از ابتدای تایم‌لاین اگر cursor نداشته باشیم)
+func (repo *TimelineRepositoryDatabase) GetTimelinePage(ctx context.Context, userID string, cursor *timelineEntity.Cursor, limit int64) (*timelinePort.TimelinePage, error) {
+	if limit <= 0 {
+		return &timelinePort.TimelinePage{Posts: []*postPort.PostDTO{}}, nil
+	}
+
+	page := &timelinePort.TimelinePage{Posts: []*postPort.PostDTO{}}
+	shown := make(map[string]bool, limit)
+	after := cursor
+	// repost های تکراری و پست‌های حذف‌شده از صفحه کنار گذاشته می‌شوند؛ صفحه تا pageRefillRounds بار
+	// از ادامه‌ی تایم‌لاین پر می‌شود تا کوتاه برنگردد
+	for round := 0; round < pageRefillRounds; round++ {
+		need := limit - int64(len(page.Posts))
+		entries, err := repo.timelineEntries(ctx, userID, after, need)
+		if err != nil {
+			return nil, err
+		}
+		if cursor == nil && round == 0 && len(entries) > 0 {
+			first := entries[0]
+			page.LatestCursor = timelineEntity.Cursor{Score: first.Score, PostID: first.PostID}.Encode()
+		}
+
+		posts, err := repo.hydrate(ctx, userID, entries, shown)
+		if err != nil {
+			return nil, err
+		}
+		page.Posts = append(page.Posts, posts...)
+
+		// صفحه‌ی ناقص یعنی به انتهای تایم‌لاین رسیده‌ایم
+		if int64(len(entries)) < need {
+			page.NextCursor = ""
+			break
+		}
+		last := entries[len(entries)-1]
+		after = &timelineEntity.Cursor{Score: last.Score, PostID: last.PostID}
+		page.NextCursor = after.Encode()
+		if int64(len(page.Posts)) >= limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+// CountNewer تعداد پست‌های تایم‌لاین جدیدتر از since؛ از ZCOUNT روی ZSET کاربر استفاده می‌شود
+// و اگر Redis در دسترس نباشد یا ZSET وجود نداشته باشد از جدول timeline شمرده می‌شود
+func (repo *TimelineRepositoryDatabase) CountNewer(ctx context.Context, userID string, since *timelineEntity.Cursor) (int64, error) {
+	pushed, err := repo.redisCountNewer(ctx, userID, since)
+	if err != nil {
+		if err != redis.Nil {
+			fmt.Println("Warning: Redis unavailable, counting new posts from MySQL:", err)
+		}
+		pushed, err = repo.databaseCountNewer(userID, since)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	pulled, err := repo.celebrityCountNewer(userID, since)
+	if err != nil {
+		return 0, err
+	}
+	return pushed + pulled, nil
+}
+
+// GetNewerThan جدیدترین limit پست تایم‌لاین که از since جدیدترند
+func (repo *TimelineRepositoryDatabase) GetNewerThan(ctx context.Context, userID string, since *timelineEntity.Cursor, limit int64) ([]*postPort.PostDTO, error) {
+	if limit <= 0 {
+		return []*postPort.PostDTO{}, nil
+	}
+
+	entries, err := repo.timelineEntries(ctx, userID, nil, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	newer := entries[:0]
+	for _, e := range entries {
+		if !since.Newer(e.Score, e.PostID) {
+			break // بقیه‌ی پست‌ها قدیمی‌ترند
+		}
+		newer = append(newer, e)
+	}
+	return repo.hydrate(ctx, userID, newer, nil)
+}
+
+// redisCountNewer شمارش اعضای ZSET جدیدتر از since؛ اعضای هم‌امتیاز با cursor بر اساس شناسه شمرده می‌شوند.
+// نبودن ZSET با redis.Nil گزارش می‌شود
+func (repo *TimelineRepositoryDatabase) redisCountNewer(ctx context.Context, userID string, since *timelineEntity.Cursor) (int64, error) {
 	key := "timeline:" + userID
+	score := strconv.FormatFloat(since.Score, 'f', -1, 64)
 
-	// 1️⃣ گرفتن postIDها از Redis ZSET
-	postIDs, err := config.RedisClient.ZRevRange(ctx, key, start, start+limit-1).Result()
+	var exists *redis.IntCmd
+	var newer *redis.IntCmd
+	var ties *redis.StringSliceCmd
+	err := repo.Breaker.Do(func() error {
+		_, err := config.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			exists = pipe.Exists(ctx, key)
+			newer = pipe.ZCount(ctx, key, "("+score, "+inf")
+			ties = pipe.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: score, Max: score})
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if exists.Val() == 0 {
+		return 0, redis.Nil
+	}
+
+	count := newer.Val()
+	for _, pid := range ties.Val() {
+		if pid > since.PostID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// databaseCountNewer شمارش پست‌های جدیدتر از since در جدول timeline
+func (repo *TimelineRepositoryDatabase) databaseCountNewer(userID string, since *timelineEntity.Cursor) (int64, error) {
+	sinceTime := time.UnixMilli(int64(since.Score))
+	var count int64
+	err := repo.db().Table("timelines").
+		Joins("JOIN posts ON posts.id = timelines.post_id").
+		Where("timelines.user_id = ? AND posts.deleted_at IS NULL", userID).
+		Where("posts.created_at > ? OR (posts.created_at = ? AND posts.id > ?)", sinceTime, sinceTime, since.PostID).
+		Count(&count).Error
+	return count, err
+}
+
+// celebrityCountNewer شمارش پست‌های جدیدتر از since از نویسندگان celebrity که کاربر دنبال می‌کند
+func (repo *TimelineRepositoryDatabase) celebrityCountNewer(userID string, since *timelineEntity.Cursor) (int64, error) {
+	if repo.CelebrityThreshold <= 0 {
+		return 0, nil
+	}
+	celebrityIDs, err := repo.followedCelebrities(userID)
+	if err != nil || len(celebrityIDs) == 0 {
+		return 0, err
+	}
+
+	sinceTime := time.UnixMilli(int64(since.Score))
+	var count int64
+	err = repo.db().Model(&postEntity.Post{}).
+		Where("user_id IN ? AND deleted_at IS NULL AND reply_to_id IS NULL", celebrityIDs).
+		Where("created_at > ? OR (created_at = ? AND id > ?)", sinceTime, sinceTime, since.PostID).
+		Count(&count).Error
+	return count, err
+}
+
+// timelineEntries اولین need پست تایم‌لاین بعد از after؛
+// پست‌های push شده (ZSET و در ادامه‌ی آن جدول timeline) با پست‌های اخیر نویسندگان celebrity که کاربر دنبال می‌کند ادغام می‌شوند
+func (repo *TimelineRepositoryDatabase) timelineEntries(ctx context.Context, userID string, after *timelineEntity.Cursor, need int64) ([]timelineEntry, error) {
+	// 1️⃣ گرفتن postIDها از Redis ZSET و در صورت نیاز از MySQL
+	entries, err := repo.pushedEntries(ctx, userID, after, need)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2️⃣ پست‌های اخیر نویسندگان celebrity (pull موقع خواندن)
+	pulled, err := repo.celebrityEntries(userID, after, need)
 	if err != nil {
 		return nil, err
 	}
 
-	posts := make([]*postPort.PostDTO, 0, len(postIDs))
+	return mergeTimelineEntries(entries, pulled, need), nil
+}
+
+// hydrate تبدیل آیتم‌های تایم‌لاین به PostDTO به همان ترتیب ZSET؛ پست‌های حذف‌شده یا ناموجود از ZSET کاربر پاک می‌شوند.
+// هر پست فقط یک بار در صفحه نمایش داده می‌شود: repost های بعدی یک پست (یا repost پستی که خودش در صفحه هست) حذف می‌شوند
+func (repo *TimelineRepositoryDatabase) hydrate(ctx context.Context, userID string, entries []timelineEntry, shown map[string]bool) ([]*postPort.PostDTO, error) {
+	posts := make([]*postPort.PostDTO, 0, len(entries))
+	if len(entries) == 0 {
+		return posts, nil
+	}
 
-	// 2️⃣ برای هر postID دیتای کامل post + user از دیتابیس
-	for _, pid := range postIDs {
-		var postEntity postEntity.Post
-		if err := config.DB.Preload("User").First(&postEntity, "id = ?", pid).Error; err != nil {
-			fmt.Println("Warning: post not found:", pid)
+	postIDs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		postIDs = append(postIDs, e.PostID)
+	}
+
+	found, err := repo.Posts.FindByIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	postsByID := make(map[string]*postEntity.Post, len(found))
+	for _, p := range found {
+		postsByID[p.ID.String()] = p
+	}
+
+	// پست‌های اصلی repost ها، پست‌های نقل‌قول‌شده و نویسندگان همه با چند کوئری دسته‌ای
+	lookup, err := postPort.LoadPostLookup(repo.Posts, repo.Users, found)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []interface{}
+	if shown == nil {
+		shown = make(map[string]bool, len(entries))
+	}
+	for _, e := range entries {
+		p, ok := postsByID[e.PostID]
+		if !ok {
+			fmt.Println("Warning: post not found:", e.PostID)
+			missing = append(missing, e.PostID)
+			continue
+		}
+
+		dto := lookup.DTO(p)
+		if p.RepostOfID != nil && dto.RepostOf == nil {
+			// پست اصلی حذف شده است
+			missing = append(missing, e.PostID)
 			continue
 		}
 
-		posts = append(posts, &postPort.PostDTO{
-			ID:      postEntity.ID.String(),
-			Content: postEntity.Content,
-			UserID:  postEntity.UserID.String(),
-			User: &userPort.UserDTO{
-				ID:       postEntity.User.ID.String(),
-				Username: postEntity.User.Username,
-				Mobile:   postEntity.User.Mobile,
-			},
-			CreatedAt: postEntity.CreatedAt.String(),
+		// شناسه‌ی پستی که در واقع نمایش داده می‌شود
+		shownID := e.PostID
+		if p.RepostOfID != nil {
+			shownID = p.RepostOfID.String()
+		}
+		if shown[shownID] {
+			continue
+		}
+		shown[shownID] = true
+
+		dto.Cursor = timelineEntity.Cursor{Score: e.Score, PostID: e.PostID}.Encode()
+		posts = append(posts, dto)
+	}
+
+	if len(missing) > 0 {
+		err := repo.Breaker.Do(func() error {
+			return config.RedisClient.ZRem(ctx, "timeline:"+userID, missing...).Err()
 		})
+		if err != nil {
+			fmt.Println("Warning: could not prune missing posts from timeline:", userID, err)
+		}
 	}
 
 	return posts, nil
 }
+
+// pushedEntries اولین need پست push شده به تایم‌لاین کاربر بعد از after؛ ZSET فقط جدیدترین پست‌ها را نگه می‌دارد (TIMELINE_MAX_LEN)
+// پس اگر صفحه از انتهای ZSET جلوتر برود ادامه از جدول timeline خوانده می‌شود.
+// اگر Redis در دسترس نباشد کل صفحه از MySQL خوانده می‌شود
+func (repo *TimelineRepositoryDatabase) pushedEntries(ctx context.Context, userID string, after *timelineEntity.Cursor, need int64) ([]timelineEntry, error) {
+	entries, err := repo.redisRange(ctx, userID, after, need)
+	if err != nil {
+		fmt.Println("Warning: Redis unavailable, serving timeline from MySQL:", err)
+		return repo.databaseEntries(userID, after, need)
+	}
+
+	// ZSET وجود ندارد (flush یا evict شده)؛ بازسازی از MySQL، در غیر این صورت این صفحه از MySQL خوانده می‌شود
+	if len(entries) == 0 && after == nil && repo.rebuildOnMiss(ctx, userID) {
+		entries, err = repo.redisRange(ctx, userID, nil, need)
+		if err != nil {
+			return repo.databaseEntries(userID, nil, need)
+		}
+	}
+	if int64(len(entries)) >= need {
+		return entries, nil
+	}
+
+	// ادامه‌ی تایم‌لاین بعد از آخرین عضو ZSET
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		after = &timelineEntity.Cursor{Score: last.Score, PostID: last.PostID}
+	}
+	older, err := repo.databaseEntries(userID, after, need-int64(len(entries)))
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, older...), nil
+}
+
+// redisRange اولین need عضو timeline ZSET بعد از after از طریق circuit breaker
+func (repo *TimelineRepositoryDatabase) redisRange(ctx context.Context, userID string, after *timelineEntity.Cursor, need int64) ([]timelineEntry, error) {
+	key := "timeline:" + userID
+	// need برای صفحه‌بندی با start می‌تواند بزرگ باشد؛ ظرفیت اولیه محدود می‌ماند
+	entries := make([]timelineEntry, 0, min(need, redisRangeCap))
+
+	if after == nil {
+		err := repo.Breaker.Do(func() error {
+			pushed, err := config.RedisClient.ZRevRangeWithScores(ctx, key, 0, need-1).Result()
+			entries = appendZ(entries, pushed, nil)
+			return err
+		})
+		return entries, err
+	}
+
+	// اعضای هم‌امتیاز با cursor هم برگردانده می‌شوند و بر اساس شناسه فیلتر می‌شوند
+	max := strconv.FormatFloat(after.Score, 'f', -1, 64)
+	var offset int64
+	for int64(len(entries)) < need {
+		var pushed []redis.Z
+		err := repo.Breaker.Do(func() error {
+			var err error
+			pushed, err = config.RedisClient.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+				Max:    max,
+				Min:    "-inf",
+				Offset: offset,
+				Count:  need,
+			}).Result()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = appendZ(entries, pushed, after)
+		if int64(len(pushed)) < need {
+			break
+		}
+		offset += int64(len(pushed))
+	}
+	if int64(len(entries)) > need {
+		entries = entries[:need]
+	}
+	return entries, nil
+}
+
+// appendZ اضافه کردن اعضای ZSET بعد از after به entries
+func appendZ(entries []timelineEntry, zs []redis.Z, after *timelineEntity.Cursor) []timelineEntry {
+	for _, z := range zs {
+		pid, ok := z.Member.(string)
+		if !ok || (after != nil && !after.After(z.Score, pid)) {
+			continue
+		}
+		entries = append(entries, timelineEntry{PostID: pid, Score: z.Score})
+	}
+	return entries
+}
+
+// rebuildOnMiss بازسازی ZSET کاربر در صورتی که درخواست دیگری در حال بازسازی آن نباشد
+func (repo *TimelineRepositoryDatabase) rebuildOnMiss(ctx context.Context, userID string) bool {
+	lockKey := "timeline:" + userID + ":rebuild"
+	var locked bool
+	err := repo.Breaker.Do(func() error {
+		var err error
+		locked, err = config.RedisClient.SetNX(ctx, lockKey, 1, rebuildLockTTL).Result()
+		return err
+	})
+	if err != nil || !locked {
+		return false
+	}
+	defer config.RedisClient.Del(ctx, lockKey)
+
+	count, err := repo.Rebuild(ctx, userID)
+	if err != nil {
+		fmt.Println("Warning: could not rebuild timeline:", userID, err)
+		return false
+	}
+	return count > 0
+}
+
+// Rebuild پر کردن timeline ZSET کاربر با جدیدترین پست‌های جدول timeline؛
+// امتیاز اعضای موجود با زمان ساخت پست جایگزین می‌شود و اعضای با امتیاز ثانیه‌ای قدیمی حذف می‌شوند
+// (اعضایی که در این فاصله push شده‌اند همان امتیاز را دارند و دست نمی‌خورند)
+func (repo *TimelineRepositoryDatabase) Rebuild(ctx context.Context, userID string) (int64, error) {
+	limit := repo.MaxLen
+	if limit <= 0 {
+		limit = defaultRebuildLen
+	}
+
+	entries, err := repo.databaseEntries(userID, nil, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	key := "timeline:" + userID
+	members := make([]*redis.Z, 0, len(entries))
+	for _, e := range entries {
+		members = append(members, &redis.Z{Score: e.Score, Member: e.PostID})
+	}
+
+	err = repo.Breaker.Do(func() error {
+		_, err := config.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZRemRangeByScore(ctx, key, "-inf", legacyScoreMax)
+			pipe.ZAdd(ctx, key, members...)
+			if repo.MaxLen > 0 {
+				pipe.ZRemRangeByRank(ctx, key, 0, -(repo.MaxLen + 1))
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(entries)), nil
+}
+
+// LegacyTimelineUserIDs یک صفحه از SCAN کلیدهای timeline:* و کاربرانی که ZSET آن‌ها هنوز امتیاز ثانیه‌ای قدیمی دارد؛
+// next صفر یعنی پیمایش تمام شده است
+func (repo *TimelineRepositoryDatabase) LegacyTimelineUserIDs(ctx context.Context, cursor uint64, count int64) ([]string, uint64, error) {
+	var keys []string
+	next := cursor
+	err := repo.Breaker.Do(func() error {
+		var err error
+		keys, next, err = config.RedisClient.ScanType(ctx, cursor, "timeline:*", count, "zset").Result()
+		return err
+	})
+	if err != nil || len(keys) == 0 {
+		return nil, next, err
+	}
+
+	counts := make([]*redis.IntCmd, len(keys))
+	err = repo.Breaker.Do(func() error {
+		_, err := config.RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				counts[i] = pipe.ZCount(ctx, key, "-inf", legacyScoreMax)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, next, err
+	}
+
+	userIDs := make([]string, 0, len(keys))
+	for i, key := range keys {
+		if counts[i].Val() > 0 {
+			userIDs = append(userIDs, strings.TrimPrefix(key, "timeline:"))
+		}
+	}
+	return userIDs, next, nil
+}
+
+// ActiveUserIDs کاربرانی که از since به بعد پستی در جدول timeline دریافت کرده‌اند، به ترتیب شناسه و بعد از afterUserID
+func (repo *TimelineRepositoryDatabase) ActiveUserIDs(ctx context.Context, since time.Time, afterUserID string, limit int) ([]string, error) {
+	q := repo.db().Model(&timelineEntity.Timeline{}).
+		Distinct("user_id").
+		Where("created_at >= ?", since)
+	if afterUserID != "" {
+		q = q.Where("user_id > ?", afterUserID)
+	}
+
+	var userIDs []string
+	if err := q.Order("user_id").Limit(limit).Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// FindAuthorPostIDs شناسه‌ی حداکثر limit پست از authorID که در جدول timeline کاربر ثبت شده‌اند (حذف‌شده‌ها هم)
+func (repo *TimelineRepositoryDatabase) FindAuthorPostIDs(ctx context.Context, userID, authorID string, limit int) ([]string, error) {
+	var postIDs []string
+	err := repo.db().Table("timelines").
+		Joins("JOIN posts ON posts.id = timelines.post_id").
+		Where("timelines.user_id = ? AND posts.user_id = ?", userID, authorID).
+		Limit(limit).
+		Pluck("timelines.post_id", &postIDs).Error
+	return postIDs, err
+}
+
+// RemovePosts حذف ردیف‌های timeline کاربر برای پست‌های داده‌شده
+func (repo *TimelineRepositoryDatabase) RemovePosts(ctx context.Context, userID string, postIDs []string) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	return repo.db().
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Delete(&timelineEntity.Timeline{}).Error
+}
+
+// FindPostUserIDs حداکثر limit کاربری که پست در جدول timeline آن‌ها ثبت شده است
+func (repo *TimelineRepositoryDatabase) FindPostUserIDs(ctx context.Context, postID string, limit int) ([]string, error) {
+	var userIDs []string
+	err := repo.db().Model(&timelineEntity.Timeline{}).
+		Where("post_id = ?", postID).
+		Limit(limit).
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// FindUsersWithPost آن‌هایی از userIDs که خود پست postID یا repost حذف‌نشده‌ای از آن در جدول timeline آن‌ها ثبت شده است
+func (repo *TimelineRepositoryDatabase) FindUsersWithPost(ctx context.Context, postID string, userIDs []string) ([]string, error) {
+	var found []string
+	if len(userIDs) == 0 {
+		return found, nil
+	}
+	err := repo.db().Table("timelines").
+		Joins("JOIN posts ON posts.id = timelines.post_id").
+		Where("timelines.user_id IN ? AND posts.deleted_at IS NULL", userIDs).
+		Where("posts.id = ? OR posts.repost_of_id = ?", postID, postID).
+		Distinct().
+		Pluck("timelines.user_id", &found).Error
+	return found, err
+}
+
+// RemovePostForUsers حذف ردیف‌های timeline یک پست برای کاربران داده‌شده
+func (repo *TimelineRepositoryDatabase) RemovePostForUsers(ctx context.Context, postID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return repo.db().
+		Where("post_id = ? AND user_id IN ?", postID, userIDs).
+		Delete(&timelineEntity.Timeline{}).Error
+}
+
+// databaseEntries خواندن تایم‌لاین از جدول timeline به همان ترتیب ZSET (زمان ساخت پست و سپس شناسه، نزولی)
+// و فقط بعد از after در صورتی که داده شده باشد
+func (repo *TimelineRepositoryDatabase) databaseEntries(userID string, after *timelineEntity.Cursor, limit int64) ([]timelineEntry, error) {
+	q := repo.db().Table("timelines").
+		Select("posts.id, posts.created_at").
+		Joins("JOIN posts ON posts.id = timelines.post_id").
+		Where("timelines.user_id = ? AND posts.deleted_at IS NULL", userID)
+	if after != nil {
+		afterTime := time.UnixMilli(int64(after.Score))
+		q = q.Where("posts.created_at < ? OR (posts.created_at = ? AND posts.id < ?)", afterTime, afterTime, after.PostID)
+	}
+
+	var rows []struct {
+		ID        string
+		CreatedAt time.Time
+	}
+	if err := q.Order("posts.created_at DESC, posts.id DESC").Limit(int(limit)).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]timelineEntry, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, timelineEntry{PostID: r.ID, Score: timelineEntity.Score(r.CreatedAt)})
+	}
+	return entries, nil
+}
+
+// celebrityEntries اولین limit پست بعد از after از نویسندگانی که کاربر دنبال می‌کند و followers آن‌ها از CelebrityThreshold بیشتر است
+func (repo *TimelineRepositoryDatabase) celebrityEntries(userID string, after *timelineEntity.Cursor, limit int64) ([]timelineEntry, error) {
+	if repo.CelebrityThreshold <= 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	celebrityIDs, err := repo.followedCelebrities(userID)
+	if err != nil || len(celebrityIDs) == 0 {
+		return nil, err
+	}
+
+	// پاسخ‌های celebrity ها pull نمی‌شوند و فقط در thread دیده می‌شوند
+	q := repo.db().Select("id", "created_at").
+		Where("user_id IN ? AND deleted_at IS NULL AND reply_to_id IS NULL", celebrityIDs)
+	if after != nil {
+		afterTime := time.UnixMilli(int64(after.Score))
+		q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", afterTime, afterTime, after.PostID)
+	}
+
+	var posts []*postEntity.Post
+	if err := q.Order("created_at DESC, id DESC").
+		Limit(int(limit)).
+		Find(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]timelineEntry, 0, len(posts))
+	for _, p := range posts {
+		entries = append(entries, timelineEntry{PostID: p.ID.String(), Score: timelineEntity.Score(p.CreatedAt)})
+	}
+	return entries, nil
+}
+
+// followedCelebrities نویسندگانی که کاربر دنبال می‌کند و followers آن‌ها از CelebrityThreshold بیشتر است؛
+// به جای شمارش جدول followers از ستون follower_count کاربران خوانده می‌شود
+func (repo *TimelineRepositoryDatabase) followedCelebrities(userID string) ([]string, error) {
+	var celebrityIDs []string
+	if err := repo.db().Table("followers").
+		Joins("JOIN users ON users.id = followers.user_id").
+		Where("followers.follower_id = ? AND users.follower_count > ?", userID, repo.CelebrityThreshold).
+		Pluck("followers.user_id", &celebrityIDs).Error; err != nil {
+		return nil, err
+	}
+	return celebrityIDs, nil
+}
+
+// mergeTimelineEntries ادغام دو لیست مرتب‌شده، حذف تکراری‌ها و برگرداندن limit آیتم اول؛
+// ترتیب مثل ZREVRANGE است: امتیاز نزولی و در امتیاز برابر شناسه‌ی نزولی
+func mergeTimelineEntries(a, b []timelineEntry, limit int64) []timelineEntry {
+	all := make([]timelineEntry, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, list := range [][]timelineEntry{a, b} {
+		for _, e := range list {
+			if seen[e.PostID] {
+				continue
+			}
+			seen[e.PostID] = true
+			all = append(all, e)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Score != all[j].Score {
+			return all[i].Score > all[j].Score
+		}
+		return all[i].PostID > all[j].PostID
+	})
+
+	if int64(len(all)) > limit {
+		all = all[:limit]
+	}
+	return all
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeTimelineEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  []timelineEntry
+		limit int64
+		want  []timelineEntry
+	}{
+		{
+			name:  "interleaves by score",
+			a:     []timelineEntry{{"p5", 5}, {"p3", 3}, {"p1", 1}},
+			b:     []timelineEntry{{"c4", 4}, {"c2", 2}},
+			limit: 10,
+			want:  []timelineEntry{{"p5", 5}, {"c4", 4}, {"p3", 3}, {"c2", 2}, {"p1", 1}},
+		},
+		{
+			name:  "equal scores ordered by id descending",
+			a:     []timelineEntry{{"a", 7}},
+			b:     []timelineEntry{{"b", 7}},
+			limit: 10,
+			want:  []timelineEntry{{"b", 7}, {"a", 7}},
+		},
+		{
+			name:  "duplicates kept once",
+			a:     []timelineEntry{{"x", 3}, {"y", 2}},
+			b:     []timelineEntry{{"x", 3}},
+			limit: 10,
+			want:  []timelineEntry{{"x", 3}, {"y", 2}},
+		},
+		{
+			name:  "truncated to limit",
+			a:     []timelineEntry{{"p3", 3}, {"p1", 1}},
+			b:     []timelineEntry{{"c2", 2}},
+			limit: 2,
+			want:  []timelineEntry{{"p3", 3}, {"c2", 2}},
+		},
+		{
+			name:  "empty inputs",
+			limit: 5,
+			want:  []timelineEntry{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeTimelineEntries(tt.a, tt.b, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeTimelineEntries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
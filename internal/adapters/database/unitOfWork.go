@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"virast/internal/config"
+	uowPort "virast/internal/ports/uow"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWorkDatabase پیاده‌سازی UnitOfWork با تراکنش GORM
+type UnitOfWorkDatabase struct{}
+
+// NewUnitOfWorkDatabase سازنده UnitOfWorkDatabase
+func NewUnitOfWorkDatabase() *UnitOfWorkDatabase {
+	return &UnitOfWorkDatabase{}
+}
+
+// Do اجرای fn با مخزن‌هایی که همگی روی یک تراکنش کار می‌کنند
+func (u *UnitOfWorkDatabase) Do(ctx context.Context, fn func(repos uowPort.Repositories) error) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(uowPort.Repositories{
+			Posts:     &PostRepositoryDatabase{tx: tx},
+			Fanouts:   &FanoutRepositoryDatabase{tx: tx},
+			Timelines: &TimelineRepositoryDatabase{tx: tx},
+			Followers: &FollowerRepositoryDatabase{tx: tx},
+		})
+	})
+}
@@ -1,8 +1,12 @@
 package database
 
 import (
+	"errors"
 	"virast/internal/config"
 	"virast/internal/core/user"
+	userPort "virast/internal/ports/user"
+
+	"gorm.io/gorm"
 )
 
 // UserRepositoryDatabase پیاده‌سازی UserRepository برای دیتابیس
@@ -31,7 +35,22 @@ func (repo *UserRepositoryDatabase) FindByUsernameOrMobile(username, mobile stri
 func (repo *UserRepositoryDatabase) FindByUsername(username string) (*user.User, error) {
 	var user user.User
 	if err := config.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, userPort.ErrUserNotFound
+		}
 		return nil, err
 	}
 	return &user, nil
 }
+
+// FindByIDs بازیابی دسته‌ای کاربران با یک کوئری
+func (repo *UserRepositoryDatabase) FindByIDs(ids []string) ([]*user.User, error) {
+	var users []*user.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := config.DB.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -26,21 +26,44 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenStr := parts[1]
-
-		// پارس و اعتبارسنجی JWT
-		claims := &jwt.StandardClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		authenticate(c, parts[1])
+	}
+}
+
+// JWTQueryAuthMiddleware مثل JWTAuthMiddleware ولی توکن را از پارامتر token هم می‌پذیرد؛
+// برای WebSocket و EventSource مرورگر که امکان تنظیم هدر Authorization را ندارند
+func JWTQueryAuthMiddleware() gin.HandlerFunc {
+	headerAuth := JWTAuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			headerAuth(c)
 			return
 		}
 
-		// userID را در context ذخیره می‌کنیم
-		c.Set("userID", claims.Subject)
+		tokenStr := c.Query("token")
+		if tokenStr == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header or token query parameter required"})
+			return
+		}
 
-		c.Next()
+		authenticate(c, tokenStr)
 	}
 }
+
+// authenticate اعتبارسنجی توکن و ذخیره‌ی userID در context
+func authenticate(c *gin.Context, tokenStr string) {
+	// پارس و اعتبارسنجی JWT
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	// userID را در context ذخیره می‌کنیم
+	c.Set("userID", claims.Subject)
+
+	c.Next()
+}
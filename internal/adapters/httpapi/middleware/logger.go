@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenParam پارامتر token در query string که JWTQueryAuthMiddleware می‌پذیرد
+var tokenParam = regexp.MustCompile(`([?&]token=)[^&]*`)
+
+// Logger همان لاگ درخواست gin.Logger ولی با حذف مقدار پارامتر token از مسیر تا JWT در لاگ‌ها ثبت نشود
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Formatter: func(param gin.LogFormatterParams) string {
+			var statusColor, methodColor, resetColor string
+			if param.IsOutputColor() {
+				statusColor = param.StatusCodeColor()
+				methodColor = param.MethodColor()
+				resetColor = param.ResetColor()
+			}
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				statusColor, param.StatusCode, resetColor,
+				param.Latency,
+				param.ClientIP,
+				methodColor, param.Method, resetColor,
+				RedactToken(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// RedactToken جایگزینی مقدار پارامتر token در path?query با REDACTED
+func RedactToken(path string) string {
+	return tokenParam.ReplaceAllString(path, "${1}REDACTED")
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import "testing"
+
+func TestRedactToken(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"no query", "/ws", "/ws"},
+		{"only token", "/ws?token=abc.def.ghi", "/ws?token=REDACTED"},
+		{"token among params", "/timeline/stream?last_event_id=x&token=abc&foo=1", "/timeline/stream?last_event_id=x&token=REDACTED&foo=1"},
+		{"similar name untouched", "/timeline?mytoken=abc", "/timeline?mytoken=abc"},
+		{"empty token", "/ws?token=", "/ws?token=REDACTED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactToken(tt.path); got != tt.want {
+				t.Errorf("RedactToken(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package httpapi
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	postPort "virast/internal/ports/post"
+	timelinePort "virast/internal/ports/timeline"
+	userPort "virast/internal/ports/user"
 
 	"github.com/gin-gonic/gin"
 )
@@ -33,3 +38,220 @@ func (ctl *PostController) CreatePost(c *gin.Context) {
 	}
 	c.JSON(http.StatusCreated, res)
 }
+
+// DeletePost حذف پست؛ پست از تایم‌لاین followers هم در پس‌زمینه حذف می‌شود
+func (ctl *PostController) DeletePost(c *gin.Context) {
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	err := ctl.pc.DeletePost(c.Request.Context(), c.Param("id"), userID.(string))
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if errors.Is(err, postPort.ErrNotPostAuthor) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the author can delete this post"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete post"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// EditPost ویرایش محتوای پست توسط نویسنده
+func (ctl *PostController) EditPost(c *gin.Context) {
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	res, err := ctl.pc.EditPost(c.Request.Context(), c.Param("id"), userID.(string), req.Content)
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if errors.Is(err, postPort.ErrNotPostAuthor) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the author can edit this post"})
+		return
+	}
+	if errors.Is(err, postPort.ErrEditWindowClosed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "edit window has expired"})
+		return
+	}
+	if errors.Is(err, postPort.ErrRepostNotEditable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reposts cannot be edited"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not edit post"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetPostHistory نسخه‌های قبلی پست
+func (ctl *PostController) GetPostHistory(c *gin.Context) {
+	res, err := ctl.pc.GetPostHistory(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch post history"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetPost نمایش یک پست با اطلاعات نویسنده
+func (ctl *PostController) GetPost(c *gin.Context) {
+	res, err := ctl.pc.GetPost(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch post"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// GetUserPosts پست‌های یک کاربر از جدید به قدیم؛ next_cursor هر صفحه برای درخواست صفحه‌ی بعد فرستاده می‌شود
+func (ctl *PostController) GetUserPosts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	res, err := ctl.pc.GetUserPosts(c.Request.Context(), c.Param("username"), c.Query("cursor"), limit)
+	if errors.Is(err, timelinePort.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if errors.Is(err, userPort.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch posts"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// CreateReply ثبت پاسخ به پست
+func (ctl *PostController) CreateReply(c *gin.Context) {
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	res, err := ctl.pc.CreateReply(c.Request.Context(), c.Param("id"), req.Content, userID.(string))
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create reply"})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+// GetThread پست و پاسخ‌های آن؛ cursor و limit پاسخ‌های مستقیم را صفحه‌بندی می‌کنند
+func (ctl *PostController) GetThread(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	res, err := ctl.pc.GetThread(c.Request.Context(), c.Param("id"), c.Query("cursor"), limit)
+	if errors.Is(err, timelinePort.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch thread"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Repost انتشار دوباره‌ی پست برای followers کاربر
+func (ctl *PostController) Repost(c *gin.Context) {
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	res, err := ctl.pc.Repost(c.Request.Context(), c.Param("id"), userID.(string))
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if errors.Is(err, postPort.ErrAlreadyReposted) {
+		c.JSON(http.StatusConflict, gin.H{"error": "post already reposted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not repost"})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+// QuotePost انتشار پست جدید که پست دیگری را نقل‌قول می‌کند
+func (ctl *PostController) QuotePost(c *gin.Context) {
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	res, err := ctl.pc.QuotePost(c.Request.Context(), c.Param("id"), req.Content, userID.(string))
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not quote post"})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
@@ -3,8 +3,10 @@ package httpapi
 import (
 	"context"
 	"virast/internal/adapters/httpapi/middleware"
+	eventsPort "virast/internal/ports/events"
 	followerPort "virast/internal/ports/follower"
 	postPort "virast/internal/ports/post"
+	timelinePort "virast/internal/ports/timeline"
 	userPort "virast/internal/ports/user"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +20,15 @@ type UserUseCase interface {
 
 type PostUseCase interface {
 	CreatePost(ctx context.Context, content, userID string) (*postPort.PostDTO, error)
+	DeletePost(ctx context.Context, postID, userID string) error
+	EditPost(ctx context.Context, postID, userID, content string) (*postPort.PostDTO, error)
+	GetPostHistory(ctx context.Context, postID string) (*postPort.PostHistoryDTO, error)
+	GetPost(ctx context.Context, postID string) (*postPort.PostDTO, error)
+	GetUserPosts(ctx context.Context, username, cursor string, limit int) (*postPort.UserPostsPage, error)
+	CreateReply(ctx context.Context, parentID, content, userID string) (*postPort.PostDTO, error)
+	GetThread(ctx context.Context, postID, cursor string, limit int) (*postPort.ThreadDTO, error)
+	Repost(ctx context.Context, postID, userID string) (*postPort.PostDTO, error)
+	QuotePost(ctx context.Context, postID, content, userID string) (*postPort.PostDTO, error)
 }
 
 type FollowerUseCase interface {
@@ -30,6 +41,13 @@ type FollowerUseCase interface {
 
 type TimelineUseCase interface {
 	GetTimelineByUserID(ctx context.Context, userID string, start int64, limit int64) ([]*postPort.PostDTO, error)
+	GetTimelinePage(ctx context.Context, userID, cursor string, limit int64) (*timelinePort.TimelinePage, error)
+	GetNewPosts(ctx context.Context, userID, since string, withPosts bool, limit int64) (*timelinePort.NewPostsDTO, error)
+	StreamTimeline(ctx context.Context, userID, lastEventID string) (<-chan *eventsPort.Event, error)
+}
+
+type NotificationUseCase interface {
+	Subscribe(ctx context.Context, userID string) (<-chan *eventsPort.Event, error)
 }
 
 // فقط روتینگ: UseCase از بیرون تزریق می‌شود
@@ -38,12 +56,17 @@ func SetupRoutes(
 	postUC PostUseCase,
 	followerUC FollowerUseCase,
 	timelineUC TimelineUseCase,
+	notificationUC NotificationUseCase,
+	wsAllowedOrigins []string, // origin هایی که اجازه‌ی اتصال WebSocket از مرورگر دارند
 ) *gin.Engine {
-	r := gin.Default()
+	// مثل gin.Default ولی لاگ درخواست بدون مقدار پارامتر token
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery())
 	uc := NewUserController(userUC)
 	pc := NewPostController(postUC)
 	fc := NewFollowerController(followerUC)
 	tc := NewTimelineController(timelineUC)
+	wc := NewWebSocketController(notificationUC, wsAllowedOrigins)
 
 	// مسیرهای ثبت‌نام و ورود بدون JWT Middleware
 	r.POST("/register", uc.RegisterUser)
@@ -51,6 +74,20 @@ func SetupRoutes(
 
 	// مسیر ایجاد پست با JWT Middleware
 	r.POST("/post", middleware.JWTAuthMiddleware(), pc.CreatePost)
+	// حذف پست فقط توسط نویسنده
+	r.DELETE("/posts/:id", middleware.JWTAuthMiddleware(), pc.DeletePost)
+	// ویرایش پست در مهلت POST_EDIT_WINDOW و تاریخچه‌ی نسخه‌های آن
+	r.PATCH("/posts/:id", middleware.JWTAuthMiddleware(), pc.EditPost)
+	r.GET("/posts/:id/history", middleware.JWTAuthMiddleware(), pc.GetPostHistory)
+	// نمایش یک پست و صفحه‌ی پروفایل (پست‌های یک کاربر با cursor)
+	r.GET("/posts/:id", middleware.JWTAuthMiddleware(), pc.GetPost)
+	r.GET("/users/:username/posts", middleware.JWTAuthMiddleware(), pc.GetUserPosts)
+	// پاسخ به پست و گفتگوی آن
+	r.POST("/posts/:id/replies", middleware.JWTAuthMiddleware(), pc.CreateReply)
+	r.GET("/posts/:id/thread", middleware.JWTAuthMiddleware(), pc.GetThread)
+	// repost و نقل‌قول؛ لغو repost با حذف خود repost انجام می‌شود
+	r.POST("/posts/:id/repost", middleware.JWTAuthMiddleware(), pc.Repost)
+	r.POST("/posts/:id/quote", middleware.JWTAuthMiddleware(), pc.QuotePost)
 
 	// مسیرهای دنبال کردن و دریافت دنبال‌کنندگان با JWT Middleware
 	r.POST("/follow", middleware.JWTAuthMiddleware(), fc.FollowUser)
@@ -60,5 +97,10 @@ func SetupRoutes(
 
 	//
 	r.GET("/timeline", middleware.JWTAuthMiddleware(), tc.GetTimelineByUserID)
+	r.GET("/timeline/new", middleware.JWTAuthMiddleware(), tc.GetNewPosts)
+	r.GET("/timeline/stream", middleware.JWTQueryAuthMiddleware(), tc.StreamTimeline)
+
+	// WebSocket برای رویدادهای زنده (تایم‌لاین، follow و اعلان‌ها)؛ توکن از هدر یا پارامتر token
+	r.GET("/ws", middleware.JWTQueryAuthMiddleware(), wc.Connect)
 	return r
 }
@@ -1,12 +1,19 @@
 package httpapi
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+	timelinePort "virast/internal/ports/timeline"
 
 	"github.com/gin-gonic/gin"
 )
 
+// sseHeartbeat فاصله‌ی ارسال ping روی اتصال SSE
+const sseHeartbeat = 15 * time.Second
+
 type TimelineController struct{ tc TimelineUseCase }
 
 func NewTimelineController(tc TimelineUseCase) *TimelineController {
@@ -21,28 +28,141 @@ func (ctrl *TimelineController) GetTimelineByUserID(c *gin.Context) {
 		return
 	}
 
-	// گرفتن start و limit از Query params و مقداردهی پیش‌فرض
-	startStr := c.DefaultQuery("start", "0")
-	limitStr := c.DefaultQuery("limit", "20")
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	// صفحه‌بندی قدیمی با start فقط وقتی cursor ارسال نشده باشد
+	startStr, hasStart := c.GetQuery("start")
+	if hasStart && c.Query("cursor") == "" {
+		start, err := strconv.ParseInt(startStr, 10, 64)
+		if err != nil || start < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start"})
+			return
+		}
+
+		// فراخوانی سرویس GetTimeline
+		timelinePosts, err := ctrl.tc.GetTimelineByUserID(c.Request.Context(), userID.(string), start, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch timeline"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"timeline": timelinePosts})
+		return
+	}
+
+	// صفحه‌بندی با cursor؛ next_cursor هر صفحه برای درخواست صفحه‌ی بعد فرستاده می‌شود
+	page, err := ctrl.tc.GetTimelinePage(c.Request.Context(), userID.(string), c.Query("cursor"), limit)
+	if errors.Is(err, timelinePort.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetNewPosts تعداد پست‌های جدیدتر از cursor since برای نمایش بنر «N پست جدید»؛
+// با include_posts=true خود پست‌ها هم (حداکثر limit) برگردانده می‌شوند
+func (ctrl *TimelineController) GetNewPosts(c *gin.Context) {
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	since := c.Query("since")
+	if since == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since is required"})
+		return
+	}
 
-	start, err := strconv.ParseInt(startStr, 10, 64)
+	withPosts, err := strconv.ParseBool(c.DefaultQuery("include_posts", "false"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_posts"})
 		return
 	}
 
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
 		return
 	}
 
-	// فراخوانی سرویس GetTimeline
-	timelinePosts, err := ctrl.tc.GetTimelineByUserID(c.Request.Context(), userID.(string), start, limit)
+	res, err := ctrl.tc.GetNewPosts(c.Request.Context(), userID.(string), since, withPosts, limit)
+	if errors.Is(err, timelinePort.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not fetch timeline"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not count new posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// StreamTimeline ارسال پست‌های جدید تایم‌لاین با Server-Sent Events؛ شناسه‌ی هر رویداد cursor پست است
+// و کلاینت بعد از قطع اتصال با هدر Last-Event-ID از همان نقطه ادامه می‌دهد
+func (ctrl *TimelineController) StreamTimeline(c *gin.Context) {
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"timeline": timelinePosts})
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	ctx := c.Request.Context()
+	events, err := ctrl.tc.StreamTimeline(ctx, userID.(string), lastEventID)
+	if errors.Is(err, timelinePort.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "timeline stream unavailable"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // جلوگیری از بافر شدن پاسخ در nginx
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			// کامنت SSE برای زنده نگه داشتن اتصال از پشت proxy ها
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: post\ndata: %s\n\n", ev.ID, ev.Data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
 }
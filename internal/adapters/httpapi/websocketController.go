@@ -0,0 +1,168 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	eventsPort "virast/internal/ports/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second    // مهلت نوشتن هر پیام
+	wsPongWait       = 60 * time.Second    // اگر در این مدت پیامی (یا pong) نرسد اتصال بسته می‌شود
+	wsPingPeriod     = wsPongWait * 9 / 10 // فاصله‌ی ping سرور؛ باید از wsPongWait کمتر باشد
+	wsMaxMessageSize = 4096                // حداکثر اندازه‌ی پیام کلاینت
+)
+
+// انواع پیام‌هایی که فقط روی WebSocket رد و بدل می‌شوند
+const (
+	wsTypePing  = "ping"
+	wsTypePong  = "pong"
+	wsTypeError = "error"
+)
+
+type WebSocketController struct {
+	nc       NotificationUseCase
+	upgrader websocket.Upgrader
+}
+
+// NewWebSocketController اتصال مرورگر فقط از allowedOrigins پذیرفته می‌شود؛ اگر لیست خالی باشد فقط همان host سرور.
+// درخواست بدون هدر Origin (کلاینت‌های غیر مرورگر) همیشه پذیرفته می‌شود
+func NewWebSocketController(nc NotificationUseCase, allowedOrigins []string) *WebSocketController {
+	ctl := &WebSocketController{
+		nc: nc,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+	if len(allowedOrigins) > 0 {
+		allowed := make(map[string]bool, len(allowedOrigins))
+		for _, o := range allowedOrigins {
+			allowed[strings.ToLower(strings.TrimRight(o, "/"))] = true
+		}
+		ctl.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || allowed[strings.ToLower(origin)]
+		}
+	}
+	return ctl
+}
+
+// Connect ارتقای اتصال به WebSocket و ارسال همه‌ی رویدادهای کاربر (پست‌های تایم‌لاین، follow ها و ...)؛
+// کلاینت می‌تواند {"type":"ping"} بفرستد و {"type":"pong"} دریافت کند
+func (ctl *WebSocketController) Connect(c *gin.Context) {
+	// گرفتن userID از context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	conn, err := ctl.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade خودش پاسخ خطا را نوشته است
+		log.Println("Warning: websocket upgrade failed:", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := ctl.nc.Subscribe(ctx, userID.(string))
+	if err != nil {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "events unavailable"),
+			time.Now().Add(wsWriteWait))
+		conn.Close()
+		return
+	}
+
+	replies := make(chan *eventsPort.Event, 8)
+	go ctl.writePump(ctx, cancel, conn, events, replies)
+	ctl.readPump(ctx, conn, replies)
+}
+
+// readPump خواندن پیام‌های کلاینت تا بسته شدن اتصال
+func (ctl *WebSocketController) readPump(ctx context.Context, conn *websocket.Conn, replies chan<- *eventsPort.Event) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg struct {
+			Type string `json:"type"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				ctl.reply(ctx, replies, wsTypeError, "invalid message")
+				continue
+			}
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		switch msg.Type {
+		case wsTypePing:
+			ctl.reply(ctx, replies, wsTypePong, nil)
+		default:
+			ctl.reply(ctx, replies, wsTypeError, "unsupported message type")
+		}
+	}
+}
+
+func (ctl *WebSocketController) reply(ctx context.Context, replies chan<- *eventsPort.Event, eventType string, data interface{}) {
+	ev, err := eventsPort.NewEvent(eventType, "", data)
+	if err != nil {
+		return
+	}
+	select {
+	case replies <- ev:
+	case <-ctx.Done():
+	}
+}
+
+// writePump تنها نویسنده‌ی اتصال: رویدادها، پاسخ‌ها و ping سرور
+func (ctl *WebSocketController) writePump(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, events <-chan *eventsPort.Event, replies <-chan *eventsPort.Event) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		cancel()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(conn, ev); err != nil {
+				return
+			}
+		case ev := <-replies:
+			if err := writeEvent(conn, ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(conn *websocket.Conn, ev *eventsPort.Event) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(ev)
+}
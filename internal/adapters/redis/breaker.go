@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCircuitOpen درخواست به Redis به دلیل باز بودن circuit breaker ارسال نشد
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker بعد از FailureThreshold خطای پشت‌سرهم درخواست‌ها به Redis را به مدت OpenTimeout قطع می‌کند؛
+// بعد از آن یک درخواست آزمایشی (half-open) اجازه دارد و موفقیت آن مدار را دوباره می‌بندد
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool // در حالت half-open یک درخواست آزمایشی در جریان است
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: failureThreshold,
+		OpenTimeout:      openTimeout,
+	}
+}
+
+// Allow اجازه‌ی ارسال درخواست؛ در حالت باز ErrCircuitOpen برمی‌گرداند
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		log.Println("🟡 Redis circuit breaker half-open, probing")
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record ثبت نتیجه‌ی درخواستی که با Allow اجازه گرفته بود
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if isRedisFailure(err) {
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= b.FailureThreshold {
+			if b.state != breakerOpen {
+				log.Println("🔴 Redis circuit breaker opened:", err)
+			}
+			b.state = breakerOpen
+			b.openedAt = time.Now()
+			b.probing = false
+		}
+		return
+	}
+
+	if b.state != breakerClosed {
+		log.Println("🟢 Redis circuit breaker closed, Redis recovered")
+	}
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Do اجرای fn در صورت بسته بودن مدار و ثبت نتیجه‌ی آن
+func (b *CircuitBreaker) Do(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(err)
+	return err
+}
+
+// Ready آیا درخواست بعدی اجازه‌ی ارسال دارد (بدون گرفتن نوبت درخواست آزمایشی)
+func (b *CircuitBreaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		return time.Since(b.openedAt) >= b.OpenTimeout
+	case breakerHalfOpen:
+		return !b.probing
+	default:
+		return true
+	}
+}
+
+// HalfOpen آیا مدار در حال آزمایش Redis است (مهلت حالت باز تمام شده یا درخواست آزمایشی در جریان است)
+func (b *CircuitBreaker) HalfOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		return time.Since(b.openedAt) >= b.OpenTimeout
+	case breakerHalfOpen:
+		return true
+	default:
+		return false
+	}
+}
+
+// isUnavailable خطاهایی که به خود درخواست ربطی ندارند و فقط نشان می‌دهند Redis در دسترس نیست
+// (مدار باز، قطع اتصال، خطای شبکه یا سروری که هنوز آماده‌ی پاسخ نیست)
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, ErrCircuitOpen) ||
+		errors.Is(err, redis.ErrClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr) ||
+		isServerNotReady(err)
+}
+
+// isRedisFailure خطاهایی که نشانه‌ی در دسترس نبودن Redis هستند؛ redis.Nil، لغو درخواست
+// و خطای پاسخ یک دستور (مثل WRONGTYPE روی یک کلید) شمرده نمی‌شوند
+func isRedisFailure(err error) bool {
+	return err != nil && err != redis.Nil && !errors.Is(err, context.Canceled) && !isCommandError(err)
+}
+
+// isCommandError خطایی که Redis در پاسخ یک دستور برگردانده و به همان دستور یا کلید مربوط است (redis.Nil خطا نیست)
+func isCommandError(err error) bool {
+	var replyErr redis.Error
+	return err != redis.Nil && errors.As(err, &replyErr) && !isServerNotReady(err)
+}
+
+// notReadyPrefixes پاسخ‌های خطایی که وضعیت سرور را نشان می‌دهند، نه ایراد دستور
+var notReadyPrefixes = []string{"LOADING ", "READONLY ", "MASTERDOWN ", "CLUSTERDOWN ", "TRYAGAIN "}
+
+// isServerNotReady آیا Redis پاسخ داده ولی فعلاً قادر به اجرای دستور نیست (در حال بارگذاری، replica و ...)
+func isServerNotReady(err error) bool {
+	var replyErr redis.Error
+	if !errors.As(err, &replyErr) {
+		return false
+	}
+	for _, prefix := range notReadyPrefixes {
+		if strings.HasPrefix(replyErr.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// replyError خطای پاسخ Redis مثل proto.RedisError که بیرون از go-redis قابل ساخت نیست
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+func (replyError) RedisError()     {}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool // Unavailable: قطعی Redis، تلاش رکورد شمرده نمی‌شود
+		failure     bool // isRedisFailure: در circuit breaker شمرده می‌شود
+		command     bool // isCommandError: فقط به همان دستور یا کلید مربوط است
+	}{
+		{"nil", nil, false, false, false},
+		{"redis nil", redis.Nil, false, false, false},
+		{"canceled", context.Canceled, false, false, false},
+		{"circuit open", ErrCircuitOpen, true, true, false},
+		{"client closed", redis.ErrClosed, true, true, false},
+		{"connection eof", io.EOF, true, true, false},
+		{"network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true, true, false},
+		{"wrongtype", replyError("WRONGTYPE Operation against a key holding the wrong kind of value"), false, false, true},
+		{"wrapped wrongtype", fmt.Errorf("push: %w", replyError("WRONGTYPE Operation against a key holding the wrong kind of value")), false, false, true},
+		{"loading", replyError("LOADING Redis is loading the dataset in memory"), true, true, false},
+		{"readonly replica", replyError("READONLY You can't write against a read only replica."), true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnavailable(tt.err); got != tt.unavailable {
+				t.Errorf("isUnavailable(%v) = %v, want %v", tt.err, got, tt.unavailable)
+			}
+			if got := isRedisFailure(tt.err); got != tt.failure {
+				t.Errorf("isRedisFailure(%v) = %v, want %v", tt.err, got, tt.failure)
+			}
+			if got := isCommandError(tt.err); got != tt.command {
+				t.Errorf("isCommandError(%v) = %v, want %v", tt.err, got, tt.command)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	eventsPort "virast/internal/ports/events"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	eventChannelPrefix = "events:" // کانال pub/sub هر کاربر: events:<userID>
+	subscriberBuffer   = 64        // رویدادهای یک اتصال کند بعد از پر شدن این بافر دور ریخته می‌شوند
+)
+
+// EventHubRedis انتشار رویدادهای کاربران روی Redis pub/sub و توزیع آن‌ها بین اتصال‌های محلی (SSE و WebSocket).
+// هر نمونه‌ی API فقط یک اتصال PSUBSCRIBE events:* دارد، نه یک اتصال برای هر کلاینت
+type EventHubRedis struct {
+	Client  *redis.Client
+	Breaker *CircuitBreaker // در زمان قطعی Redis رویدادها دور ریخته می‌شوند؛ تایم‌لاین خودش منبع اصلی است
+
+	mu   sync.Mutex
+	subs map[string]map[chan *eventsPort.Event]struct{} // userID -> اتصال‌های محلی
+}
+
+func NewEventHubRedis(client *redis.Client, breaker *CircuitBreaker) *EventHubRedis {
+	return &EventHubRedis{
+		Client:  client,
+		Breaker: breaker,
+		subs:    make(map[string]map[chan *eventsPort.Event]struct{}),
+	}
+}
+
+// Publish ارسال رویداد به کانال همه‌ی کاربران در یک pipeline
+func (h *EventHubRedis) Publish(ctx context.Context, userIDs []string, ev *eventsPort.Event) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	return h.Breaker.Do(func() error {
+		_, err := h.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, userID := range userIDs {
+				pipe.Publish(ctx, eventChannelPrefix+userID, payload)
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// Subscribe ثبت یک اتصال محلی برای کاربر؛ کانال خروجی با لغو ctx بسته می‌شود
+func (h *EventHubRedis) Subscribe(ctx context.Context, userID string) (<-chan *eventsPort.Event, error) {
+	ch := make(chan *eventsPort.Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[chan *eventsPort.Event]struct{})
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs[userID], ch)
+		if len(h.subs[userID]) == 0 {
+			delete(h.subs, userID)
+		}
+		close(ch)
+		h.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+// Run گوش دادن به همه‌ی کانال‌های رویداد تا لغو ctx؛ go-redis بعد از قطع اتصال خودش دوباره subscribe می‌کند
+func (h *EventHubRedis) Run(ctx context.Context) {
+	pubsub := h.Client.PSubscribe(ctx, eventChannelPrefix+"*")
+	defer pubsub.Close()
+
+	log.Println("📡 Event hub subscribed to", eventChannelPrefix+"*")
+	msgs := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			h.dispatch(strings.TrimPrefix(msg.Channel, eventChannelPrefix), msg.Payload)
+		}
+	}
+}
+
+// dispatch تحویل رویداد به اتصال‌های محلی کاربر بدون بلاک شدن روی اتصال‌های کند
+func (h *EventHubRedis) dispatch(userID, payload string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[userID]
+	if len(subs) == 0 {
+		return
+	}
+
+	var ev eventsPort.Event
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		log.Println("Warning: invalid event payload:", err)
+		return
+	}
+
+	for ch := range subs {
+		select {
+		case ch <- &ev:
+		default:
+			log.Println("Warning: dropping event for slow subscriber:", userID, ev.Type)
+		}
+	}
+}
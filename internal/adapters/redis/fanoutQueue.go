@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	fanoutPort "virast/internal/ports/fanoutqueue"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	FanoutStream = "fanout:stream"
+	FanoutGroup  = "fanout-workers"
+)
+
+// FanoutQueueRedis پیاده‌سازی صف fanout با Redis Streams و consumer group
+type FanoutQueueRedis struct {
+	Client *redis.Client
+	Stream string
+	Group  string
+}
+
+func NewFanoutQueueRedis(client *redis.Client) *FanoutQueueRedis {
+	return &FanoutQueueRedis{
+		Client: client,
+		Stream: FanoutStream,
+		Group:  FanoutGroup,
+	}
+}
+
+// EnsureGroup ساخت stream و consumer group در صورت نبودن
+func (q *FanoutQueueRedis) EnsureGroup(ctx context.Context) error {
+	err := q.Client.XGroupCreateMkStream(ctx, q.Stream, q.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// Enqueue اضافه کردن پیام به stream با XADD
+func (q *FanoutQueueRedis) Enqueue(ctx context.Context, msg *fanoutPort.FanoutMessage) error {
+	return q.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.Stream,
+		Values: map[string]interface{}{
+			"queue_id":  msg.QueueID,
+			"post_id":   msg.PostID,
+			"author_id": msg.AuthorID,
+		},
+	}).Err()
+}
+
+// Consume خواندن پیام‌های جدید برای این consumer با XREADGROUP
+func (q *FanoutQueueRedis) Consume(ctx context.Context, consumer string, count int64, block time.Duration) ([]*fanoutPort.FanoutDelivery, error) {
+	streams, err := q.Client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.Group,
+		Consumer: consumer,
+		Streams:  []string{q.Stream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*fanoutPort.FanoutDelivery
+	for _, s := range streams {
+		for _, m := range s.Messages {
+			deliveries = append(deliveries, toDelivery(m.ID, m.Values))
+		}
+	}
+	return deliveries, nil
+}
+
+// ClaimStale گرفتن پیام‌هایی که بیش از minIdle در PEL یک consumer دیگر مانده‌اند (XAUTOCLAIM)
+func (q *FanoutQueueRedis) ClaimStale(ctx context.Context, consumer string, minIdle time.Duration, count int64) ([]*fanoutPort.FanoutDelivery, error) {
+	// go-redis v8 فقط پاسخ دوعضوی XAUTOCLAIM (Redis 6.2) را می‌شناسد،
+	// در Redis 7 عضو سوم (شناسه‌های حذف‌شده) هم برمی‌گردد؛ پس پاسخ را خودمان پارس می‌کنیم
+	res, err := q.Client.Do(ctx, "XAUTOCLAIM", q.Stream, q.Group, consumer,
+		minIdle.Milliseconds(), "0-0", "COUNT", count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, ok := res.([]interface{})
+	if !ok || len(reply) < 2 {
+		return nil, fmt.Errorf("unexpected XAUTOCLAIM reply: %v", res)
+	}
+	entries, ok := reply[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected XAUTOCLAIM entries: %v", reply[1])
+	}
+
+	var deliveries []*fanoutPort.FanoutDelivery
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue // پیامی که از stream حذف شده
+		}
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if k, ok := fields[i].(string); ok {
+				values[k] = fields[i+1]
+			}
+		}
+		deliveries = append(deliveries, toDelivery(id, values))
+	}
+	return deliveries, nil
+}
+
+// Ack تایید پردازش پیام‌ها؛ چون فقط یک consumer group داریم پیام از stream هم حذف می‌شود
+func (q *FanoutQueueRedis) Ack(ctx context.Context, deliveryIDs ...string) error {
+	if len(deliveryIDs) == 0 {
+		return nil
+	}
+	_, err := q.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.Stream, q.Group, deliveryIDs...)
+		pipe.XDel(ctx, q.Stream, deliveryIDs...)
+		return nil
+	})
+	return err
+}
+
+func toDelivery(id string, values map[string]interface{}) *fanoutPort.FanoutDelivery {
+	str := func(key string) string {
+		v, _ := values[key].(string)
+		return v
+	}
+	return &fanoutPort.FanoutDelivery{
+		ID: id,
+		Message: fanoutPort.FanoutMessage{
+			QueueID:  str("queue_id"),
+			PostID:   str("post_id"),
+			AuthorID: str("author_id"),
+		},
+	}
+}
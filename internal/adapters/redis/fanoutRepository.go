@@ -4,42 +4,140 @@ import (
 	"context"
 	"fmt"
 	"time"
+	"virast/internal/core/timeline"
+	fanoutPort "virast/internal/ports/fanoutqueue"
 
 	"github.com/go-redis/redis/v8"
 )
 
 type FanoutRepositoryRedis struct {
-	Client *redis.Client
+	Client  *redis.Client
+	MaxLen  int64           // حداکثر طول هر timeline ZSET؛ صفر یعنی بدون محدودیت
+	Breaker *CircuitBreaker // در زمان قطعی Redis درخواست‌ها بلافاصله با ErrCircuitOpen رد می‌شوند
 }
 
-func NewFanoutRepositoryRedis(client *redis.Client) *FanoutRepositoryRedis {
+func NewFanoutRepositoryRedis(client *redis.Client, maxLen int64, breaker *CircuitBreaker) *FanoutRepositoryRedis {
 	return &FanoutRepositoryRedis{
-		Client: client,
+		Client:  client,
+		MaxLen:  maxLen,
+		Breaker: breaker,
 	}
 }
 
-// PushPostToFollowers: اضافه کردن postID به timeline ZSET تمام followers
-func (r *FanoutRepositoryRedis) PushPostToFollowers(ctx context.Context, postID string, followerIDs []string) error {
-	fmt.Println("!!!!!!!!!!!!!!!!!!!!!!!!!")
-	fmt.Println("Pushing post", postID, "to followers:", followerIDs)
-	fmt.Println("!!!!!!!!!!!!!!!!!!!!!!!!!")
+// Available آیا circuit breaker اجازه‌ی ارسال درخواست به Redis را می‌دهد
+func (r *FanoutRepositoryRedis) Available() bool {
+	return r.Breaker.Ready()
+}
+
+// Recovering آیا circuit breaker نیمه‌باز است و فقط یک درخواست آزمایشی به Redis مجاز است
+func (r *FanoutRepositoryRedis) Recovering() bool {
+	return r.Breaker.HalfOpen()
+}
+
+// Unavailable آیا err ناشی از قطعی Redis یا باز بودن circuit breaker است
+func (r *FanoutRepositoryRedis) Unavailable(err error) bool {
+	return isUnavailable(err)
+}
+
+// PushPostToFollowers: اضافه کردن postID به timeline ZSET تمام followers با امتیاز زمان ساخت پست؛
+// همه‌ی ZADD ها (و trim طول ZSET) در یک pipeline ارسال می‌شوند.
+// با ZADD NX امتیاز عضو موجود تغییر نمی‌کند و تحویل دوباره بی‌اثر است
+func (r *FanoutRepositoryRedis) PushPostToFollowers(ctx context.Context, postID string, createdAt time.Time, followerIDs []string) error {
+	if len(followerIDs) == 0 {
+		return nil
+	}
+	if err := r.Breaker.Allow(); err != nil {
+		return err
+	}
+
+	z := &redis.Z{
+		Score:  timeline.Score(createdAt),
+		Member: postID,
+	}
+
+	pipe := r.Client.Pipeline()
+	cmds := make(map[string][]redis.Cmder, len(followerIDs))
 	for _, followerID := range followerIDs {
 		key := "timeline:" + followerID
-
-		z := &redis.Z{
-			Score:  float64(time.Now().Unix()),
-			Member: postID,
+		cmds[key] = append(cmds[key], pipe.ZAddNX(ctx, key, z))
+		if r.MaxLen > 0 {
+			// فقط MaxLen عضو جدیدتر نگه داشته می‌شوند
+			cmds[key] = append(cmds[key], pipe.ZRemRangeByRank(ctx, key, 0, -(r.MaxLen+1)))
 		}
+	}
 
-		fmt.Println("Adding to ZSET", key, "postID:", postID)
+	// خطای Exec همان خطای اولین دستور ناموفق است؛ فقط خطای اتصال کل batch را ناموفق می‌کند
+	// و خطای پاسخ Redis (مثل WRONGTYPE) برای هر کلید جداگانه گزارش می‌شود
+	_, err := pipe.Exec(ctx)
+	r.Breaker.Record(err)
+	if err != nil && !isCommandError(err) {
+		return fmt.Errorf("push post %s: %w", postID, err)
+	}
 
-		if err := r.Client.ZAdd(ctx, key, z).Err(); err != nil {
-			return err
+	failed := make(map[string]error)
+	for key, list := range cmds {
+		for _, cmd := range list {
+			if err := cmd.Err(); err != nil {
+				failed[key] = err
+				break
+			}
 		}
+	}
+	if len(failed) > 0 {
+		return &fanoutPort.PushError{PostID: postID, FailedKeys: failed}
+	}
+	return nil
+}
 
-		// optional: چاپ برای debug
-		fmt.Println("Added post", postID, "to", key)
+// AddPostsToTimeline اضافه کردن چند پست با امتیاز زمان ساخت‌شان به timeline ZSET یک کاربر (مثلاً backfill بعد از follow)
+func (r *FanoutRepositoryRedis) AddPostsToTimeline(ctx context.Context, userID string, posts map[string]time.Time) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	members := make([]*redis.Z, 0, len(posts))
+	for postID, createdAt := range posts {
+		members = append(members, &redis.Z{Score: timeline.Score(createdAt), Member: postID})
 	}
 
-	return nil
-}
\ No newline at end of file
+	key := "timeline:" + userID
+	return r.Breaker.Do(func() error {
+		_, err := r.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.ZAddNX(ctx, key, members...)
+			if r.MaxLen > 0 {
+				pipe.ZRemRangeByRank(ctx, key, 0, -(r.MaxLen + 1))
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// RemovePostsFromTimeline حذف پست‌ها از timeline ZSET یک کاربر (مثلاً بعد از آنفالو)
+func (r *FanoutRepositoryRedis) RemovePostsFromTimeline(ctx context.Context, userID string, postIDs []string) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	members := make([]interface{}, 0, len(postIDs))
+	for _, id := range postIDs {
+		members = append(members, id)
+	}
+	return r.Breaker.Do(func() error {
+		return r.Client.ZRem(ctx, "timeline:"+userID, members...).Err()
+	})
+}
+
+// RemovePostFromTimelines حذف یک پست از timeline ZSET چند کاربر در یک pipeline (مثلاً بعد از حذف پست)
+func (r *FanoutRepositoryRedis) RemovePostFromTimelines(ctx context.Context, postID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return r.Breaker.Do(func() error {
+		_, err := r.Client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, userID := range userIDs {
+				pipe.ZRem(ctx, "timeline:"+userID, postID)
+			}
+			return nil
+		})
+		return err
+	})
+}
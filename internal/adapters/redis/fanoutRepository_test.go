@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	benchFollowers = 500 // تعداد followers هر batch
+	benchMaxLen    = 800 // مثل TIMELINE_MAX_LEN تا هزینه‌ی trim هم سنجیده شود
+)
+
+// benchClient اتصال به Redis مشخص‌شده با REDIS_ADDR؛ بدون آن benchmark اجرا نمی‌شود
+func benchClient(b *testing.B) *redis.Client {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		b.Skip("REDIS_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("REDIS_PASSWORD")})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		b.Skip("redis unavailable: ", err)
+	}
+	b.Cleanup(func() { client.Close() })
+	return client
+}
+
+// benchFollowerIDs شناسه‌های followers با کلیدهای timeline:bench:* که بعد از benchmark پاک می‌شوند
+func benchFollowerIDs(b *testing.B, client *redis.Client) []string {
+	ids := make([]string, 0, benchFollowers)
+	keys := make([]string, 0, benchFollowers)
+	for i := 0; i < benchFollowers; i++ {
+		id := fmt.Sprintf("bench:%d", i)
+		ids = append(ids, id)
+		keys = append(keys, "timeline:"+id)
+	}
+	b.Cleanup(func() { client.Del(context.Background(), keys...) })
+	return ids
+}
+
+// BenchmarkPushPostToFollowersLoop حلقه‌ی قبلی PushPostToFollowers: یک ZADD همگام برای هر follower
+func BenchmarkPushPostToFollowersLoop(b *testing.B) {
+	client := benchClient(b)
+	followerIDs := benchFollowerIDs(b, client)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		postID := fmt.Sprintf("loop-post-%d", i)
+		for _, followerID := range followerIDs {
+			z := &redis.Z{Score: float64(time.Now().Unix()), Member: postID}
+			if err := client.ZAdd(ctx, "timeline:"+followerID, z).Err(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchFollowers)/b.Elapsed().Seconds(), "zadd/s")
+}
+
+// BenchmarkPushPostToFollowersPipeline پیاده‌سازی فعلی با pipeline و trim طول ZSET
+func BenchmarkPushPostToFollowersPipeline(b *testing.B) {
+	client := benchClient(b)
+	followerIDs := benchFollowerIDs(b, client)
+	ctx := context.Background()
+	repo := NewFanoutRepositoryRedis(client, benchMaxLen, NewCircuitBreaker(5, 10*time.Second))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := repo.PushPostToFollowers(ctx, fmt.Sprintf("pipeline-post-%d", i), time.Now(), followerIDs); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchFollowers)/b.Elapsed().Seconds(), "zadd/s")
+}
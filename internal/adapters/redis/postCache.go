@@ -0,0 +1,231 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"virast/internal/core/post"
+	postPort "virast/internal/ports/post"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
+)
+
+// PostRepositoryCache دکوراتور read-through روی PostRepository؛
+// هر پست در یک hash با کلید post:<id> و با TTL نگه داشته می‌شود
+type PostRepositoryCache struct {
+	postPort.PostRepository // ریپازیتوری اصلی؛ متدهایی که cache نمی‌شوند مستقیم به آن می‌رسند
+
+	Client  *redis.Client
+	TTL     time.Duration
+	Breaker *CircuitBreaker // در زمان قطعی Redis مستقیم از ریپازیتوری اصلی خوانده می‌شود
+
+	hits   int64
+	misses int64
+}
+
+func NewPostRepositoryCache(inner postPort.PostRepository, client *redis.Client, ttl time.Duration, breaker *CircuitBreaker) *PostRepositoryCache {
+	return &PostRepositoryCache{
+		PostRepository: inner,
+		Client:         client,
+		TTL:            ttl,
+		Breaker:        breaker,
+	}
+}
+
+func postCacheKey(id string) string {
+	return "post:" + id
+}
+
+// FindByID خواندن از cache و در صورت miss از ریپازیتوری اصلی
+func (c *PostRepositoryCache) FindByID(id string) (*post.Post, error) {
+	found, err := c.FindByIDs([]string{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		// پست حذف‌شده cache نمی‌شود ولی FindByID همچنان آن را برمی‌گرداند
+		return c.PostRepository.FindByID(id)
+	}
+	return found[0], nil
+}
+
+// FindByIDs خواندن دسته‌ای؛ شناسه‌های miss با یک کوئری از ریپازیتوری اصلی خوانده و در cache نوشته می‌شوند
+func (c *PostRepositoryCache) FindByIDs(ids []string) ([]*post.Post, error) {
+	ctx := context.Background()
+	posts := make([]*post.Post, 0, len(ids))
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	missing := ids
+	var cmds []*redis.StringStringMapCmd
+	err := c.Breaker.Do(func() error {
+		pipe := c.Client.Pipeline()
+		for _, id := range ids {
+			cmds = append(cmds, pipe.HGetAll(ctx, postCacheKey(id)))
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	if err == nil {
+		missing = missing[:0:0]
+		for i, cmd := range cmds {
+			if p, ok := postFromHash(cmd.Val()); ok {
+				posts = append(posts, p)
+			} else {
+				missing = append(missing, ids[i])
+			}
+		}
+	}
+	atomic.AddInt64(&c.hits, int64(len(posts)))
+	atomic.AddInt64(&c.misses, int64(len(missing)))
+
+	if len(missing) == 0 {
+		return posts, nil
+	}
+	loaded, err := c.PostRepository.FindByIDs(missing)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, loaded)
+	return append(posts, loaded...), nil
+}
+
+// Delete حذف نرم پست و حذف آن از cache
+func (c *PostRepositoryCache) Delete(id string) error {
+	if err := c.PostRepository.Delete(id); err != nil {
+		return err
+	}
+	if err := c.Invalidate(context.Background(), id); err != nil {
+		log.Println("Warning: could not invalidate cached post:", id, err)
+	}
+	return nil
+}
+
+// Update ذخیره‌ی ویرایش پست و حذف نسخه‌ی قدیمی از cache
+func (c *PostRepositoryCache) Update(p *post.Post) error {
+	if err := c.PostRepository.Update(p); err != nil {
+		return err
+	}
+	if err := c.Invalidate(context.Background(), p.ID.String()); err != nil {
+		log.Println("Warning: could not invalidate cached post:", p.ID, err)
+	}
+	return nil
+}
+
+// Invalidate حذف پست‌ها از cache بعد از ویرایش یا حذف
+func (c *PostRepositoryCache) Invalidate(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, postCacheKey(id))
+	}
+	return c.Breaker.Do(func() error {
+		return c.Client.Del(ctx, keys...).Err()
+	})
+}
+
+// Stats تعداد hit و miss از زمان شروع برنامه
+func (c *PostRepositoryCache) Stats() (hits, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
+
+// store نوشتن پست‌ها در cache؛ خطا فقط لاگ می‌شود چون داده‌ی اصلی در MySQL است
+func (c *PostRepositoryCache) store(ctx context.Context, posts []*post.Post) {
+	if len(posts) == 0 {
+		return
+	}
+	err := c.Breaker.Do(func() error {
+		pipe := c.Client.Pipeline()
+		for _, p := range posts {
+			key := postCacheKey(p.ID.String())
+			pipe.HSet(ctx, key, postToHash(p))
+			pipe.Expire(ctx, key, c.TTL)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	if err != nil {
+		log.Println("Warning: could not cache posts:", err)
+	}
+}
+
+func postToHash(p *post.Post) map[string]interface{} {
+	h := map[string]interface{}{
+		"id":          p.ID.String(),
+		"content":     p.Content,
+		"user_id":     p.UserID.String(),
+		"created_at":  p.CreatedAt.Format(time.RFC3339Nano),
+		"updated_at":  p.UpdatedAt.Format(time.RFC3339Nano),
+		"edited_at":   "",
+		"reply_to_id": "",
+		"root_id":     "",
+		"reply_count": p.ReplyCount,
+		"repost_of":   "",
+		"quoted_post": "",
+	}
+	if p.EditedAt != nil {
+		h["edited_at"] = p.EditedAt.Format(time.RFC3339Nano)
+	}
+	if p.ReplyToID != nil {
+		h["reply_to_id"] = p.ReplyToID.String()
+	}
+	if p.RootID != nil {
+		h["root_id"] = p.RootID.String()
+	}
+	if p.RepostOfID != nil {
+		h["repost_of"] = p.RepostOfID.String()
+	}
+	if p.QuotedPostID != nil {
+		h["quoted_post"] = p.QuotedPostID.String()
+	}
+	return h
+}
+
+func postFromHash(h map[string]string) (*post.Post, bool) {
+	if len(h) == 0 {
+		return nil, false
+	}
+	id, err := uuid.FromString(h["id"])
+	if err != nil {
+		return nil, false
+	}
+	userID, err := uuid.FromString(h["user_id"])
+	if err != nil {
+		return nil, false
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, h["created_at"])
+	if err != nil {
+		return nil, false
+	}
+	updatedAt, _ := time.Parse(time.RFC3339Nano, h["updated_at"])
+	p := &post.Post{
+		ID:        id,
+		Content:   h["content"],
+		UserID:    userID,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}
+	if editedAt, err := time.Parse(time.RFC3339Nano, h["edited_at"]); err == nil {
+		p.EditedAt = &editedAt
+	}
+	if replyToID, err := uuid.FromString(h["reply_to_id"]); err == nil {
+		p.ReplyToID = &replyToID
+	}
+	if rootID, err := uuid.FromString(h["root_id"]); err == nil {
+		p.RootID = &rootID
+	}
+	if repostOfID, err := uuid.FromString(h["repost_of"]); err == nil {
+		p.RepostOfID = &repostOfID
+	}
+	if quotedPostID, err := uuid.FromString(h["quoted_post"]); err == nil {
+		p.QuotedPostID = &quotedPostID
+	}
+	p.ReplyCount, _ = strconv.ParseInt(h["reply_count"], 10, 64)
+	return p, true
+}
@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+	"virast/internal/core/user"
+	userPort "virast/internal/ports/user"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
+)
+
+// UserRepositoryCache دکوراتور read-through روی UserRepository برای اطلاعات نویسنده‌ی پست‌ها؛
+// هر کاربر در یک hash با کلید user:<id> و با TTL نگه داشته می‌شود.
+// رمز عبور هیچ‌وقت cache نمی‌شود، پس FindByUsername (ورود) مستقیم به ریپازیتوری اصلی می‌رسد
+type UserRepositoryCache struct {
+	userPort.UserRepository // ریپازیتوری اصلی؛ متدهایی که cache نمی‌شوند مستقیم به آن می‌رسند
+
+	Client  *redis.Client
+	TTL     time.Duration
+	Breaker *CircuitBreaker // در زمان قطعی Redis مستقیم از ریپازیتوری اصلی خوانده می‌شود
+
+	hits   int64
+	misses int64
+}
+
+func NewUserRepositoryCache(inner userPort.UserRepository, client *redis.Client, ttl time.Duration, breaker *CircuitBreaker) *UserRepositoryCache {
+	return &UserRepositoryCache{
+		UserRepository: inner,
+		Client:         client,
+		TTL:            ttl,
+		Breaker:        breaker,
+	}
+}
+
+func userCacheKey(id string) string {
+	return "user:" + id
+}
+
+// FindByIDs خواندن دسته‌ای؛ کاربران برگشتی فیلد Password ندارند
+func (c *UserRepositoryCache) FindByIDs(ids []string) ([]*user.User, error) {
+	ctx := context.Background()
+	users := make([]*user.User, 0, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	missing := ids
+	var cmds []*redis.StringStringMapCmd
+	err := c.Breaker.Do(func() error {
+		pipe := c.Client.Pipeline()
+		for _, id := range ids {
+			cmds = append(cmds, pipe.HGetAll(ctx, userCacheKey(id)))
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	if err == nil {
+		missing = missing[:0:0]
+		for i, cmd := range cmds {
+			if u, ok := userFromHash(cmd.Val()); ok {
+				users = append(users, u)
+			} else {
+				missing = append(missing, ids[i])
+			}
+		}
+	}
+	atomic.AddInt64(&c.hits, int64(len(users)))
+	atomic.AddInt64(&c.misses, int64(len(missing)))
+
+	if len(missing) == 0 {
+		return users, nil
+	}
+	loaded, err := c.UserRepository.FindByIDs(missing)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, loaded)
+	for _, u := range loaded {
+		u.Password = ""
+	}
+	return append(users, loaded...), nil
+}
+
+// Stats تعداد hit و miss از زمان شروع برنامه
+func (c *UserRepositoryCache) Stats() (hits, misses int64) {
+	return atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses)
+}
+
+// store نوشتن کاربران در cache؛ خطا فقط لاگ می‌شود چون داده‌ی اصلی در MySQL است.
+// کاربر حذف‌شده cache نمی‌شود تا DeletedAt همیشه از ریپازیتوری اصلی خوانده شود و پست‌های او پنهان بمانند
+func (c *UserRepositoryCache) store(ctx context.Context, users []*user.User) {
+	active := make([]*user.User, 0, len(users))
+	for _, u := range users {
+		if u.DeletedAt == nil {
+			active = append(active, u)
+		}
+	}
+	if len(active) == 0 {
+		return
+	}
+	err := c.Breaker.Do(func() error {
+		pipe := c.Client.Pipeline()
+		for _, u := range active {
+			key := userCacheKey(u.ID.String())
+			pipe.HSet(ctx, key, userToHash(u))
+			pipe.Expire(ctx, key, c.TTL)
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	if err != nil {
+		log.Println("Warning: could not cache users:", err)
+	}
+}
+
+func userToHash(u *user.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       u.ID.String(),
+		"name":     u.Name,
+		"family":   u.Family,
+		"username": u.Username,
+		"mobile":   u.Mobile,
+	}
+}
+
+func userFromHash(h map[string]string) (*user.User, bool) {
+	if len(h) == 0 {
+		return nil, false
+	}
+	id, err := uuid.FromString(h["id"])
+	if err != nil {
+		return nil, false
+	}
+	return &user.User{
+		ID:       id,
+		Name:     h["name"],
+		Family:   h["family"],
+		Username: h["username"],
+		Mobile:   h["mobile"],
+	}, true
+}
@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+	"virast/internal/core/post"
+	"virast/internal/core/user"
+	postPort "virast/internal/ports/post"
+	userPort "virast/internal/ports/user"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gofrs/uuid"
+)
+
+// fakeUserRepository ریپازیتوری اصلی کاربران در حافظه؛ reads تعداد کاربرانی است که از آن خوانده شده‌اند
+type fakeUserRepository struct {
+	userPort.UserRepository
+	users map[string]*user.User
+	reads int
+}
+
+func (r *fakeUserRepository) FindByIDs(ids []string) ([]*user.User, error) {
+	var found []*user.User
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok {
+			cp := *u
+			found = append(found, &cp)
+			r.reads++
+		}
+	}
+	return found, nil
+}
+
+// fakePostRepository فقط FindByIDs برای LoadPostLookup
+type fakePostRepository struct {
+	postPort.PostRepository
+}
+
+func (r *fakePostRepository) FindByIDs(ids []string) ([]*post.Post, error) {
+	return nil, nil
+}
+
+// testClient اتصال به Redis مشخص‌شده با REDIS_ADDR؛ بدون Redis واقعی cache همیشه miss است و تست معنایی ندارد
+func testClient(t *testing.T) *redis.Client {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("REDIS_PASSWORD")})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		t.Skip("redis unavailable: ", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestUserRepositoryCacheHidesDeletedAuthorOnWarmRead(t *testing.T) {
+	client := testClient(t)
+
+	author := &user.User{ID: uuid.Must(uuid.NewV4()), Username: "author"}
+	inner := &fakeUserRepository{users: map[string]*user.User{author.ID.String(): author}}
+	cache := NewUserRepositoryCache(inner, client, time.Minute, NewCircuitBreaker(5, time.Second))
+	t.Cleanup(func() { client.Del(context.Background(), userCacheKey(author.ID.String())) })
+
+	p := &post.Post{ID: uuid.Must(uuid.NewV4()), UserID: author.ID, Content: "hello"}
+	read := func() *postPort.PostDTO {
+		t.Helper()
+		lookup, err := postPort.LoadPostLookup(&fakePostRepository{}, cache, []*post.Post{p})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lookup.DTO(p)
+	}
+
+	if dto := read(); dto.User == nil {
+		t.Fatal("author missing before delete")
+	}
+
+	deletedAt := time.Now()
+	author.DeletedAt = &deletedAt
+	// مثل انقضای TTL؛ بعد از آن کاربر حذف‌شده نباید دوباره cache شود
+	if err := client.Del(context.Background(), userCacheKey(author.ID.String())).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		if dto := read(); dto.User != nil {
+			t.Fatalf("read %d after delete: got author %+v, want nil", i, dto.User)
+		}
+	}
+	if inner.reads != 3 {
+		t.Errorf("inner reads = %d, want 3 (deleted author must not be served from cache)", inner.reads)
+	}
+}
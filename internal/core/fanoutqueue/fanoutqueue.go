@@ -7,14 +7,39 @@ import (
 	"virast/internal/core/user"
 )
 
+// وضعیت‌های رکورد fanout_queue
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing" // در اختیار یک worker با lease معتبر
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+)
+
+// نوع کار هر رکورد fanout_queue
+const (
+	KindPost     = "post"     // تحویل پست PostID به followers نویسنده (UserID)
+	KindUnfollow = "unfollow" // حذف پست‌های UserID از تایم‌لاین TargetUserID بعد از آنفالو
+	KindBackfill = "backfill" // اضافه کردن آخرین پست‌های UserID به تایم‌لاین TargetUserID بعد از follow
+	KindRetract  = "retract"  // حذف پست PostID از تایم‌لاین همه‌ی کسانی که آن را دریافت کرده‌اند
+)
+
 type FanoutQueue struct {
-	ID          uuid.UUID  `gorm:"primary_key;type:char(36);default:uuid()"`
-	PostID      uuid.UUID  `gorm:"type:char(36);not null"`
-	Post        post.Post  `gorm:"foreignkey:PostID;references:ID"`
-	UserID      uuid.UUID  `gorm:"type:char(36);not null"`
-	User        user.User  `gorm:"foreignKey:UserID;references:ID"`
-	Status      string     `gorm:"type:varchar(20);not null"` // pending, done, failed
-	CreatedAt   time.Time  `gorm:"autoCreateTime"`
-	ProcessedAt *time.Time `gorm:"index"`
-	DeletedAt   *time.Time `gorm:"index"`
+	ID             uuid.UUID  `gorm:"primary_key;type:char(36);default:uuid()"`
+	Kind           string     `gorm:"type:varchar(20);not null;default:'post';index"` // post, unfollow, backfill, retract
+	PostID         *uuid.UUID `gorm:"type:char(36)"`                                  // برای کارهایی که به یک پست مربوط نیستند خالی است
+	Post           post.Post  `gorm:"foreignkey:PostID;references:ID"`
+	UserID         uuid.UUID  `gorm:"type:char(36);not null"` // نویسنده
+	User           user.User  `gorm:"foreignKey:UserID;references:ID"`
+	TargetUserID   *uuid.UUID `gorm:"type:char(36);index"` // کاربری که فقط تایم‌لاین او تغییر می‌کند (unfollow، backfill)
+	Status         string     `gorm:"type:varchar(20);not null;index"` // pending, processing, done, failed
+	ClaimedBy      string     `gorm:"type:varchar(128)"`               // شناسه worker صاحب lease
+	LeaseExpiresAt *time.Time `gorm:"index"`
+	Attempts       int        `gorm:"not null;default:0"` // تعداد تلاش‌های ناموفق
+	NextAttemptAt  *time.Time `gorm:"index"`              // تا این زمان دوباره برداشته نمی‌شود (backoff)
+	LastError      string     `gorm:"type:text"`
+	LastFollowerID string     `gorm:"type:char(36)"` // checkpoint: آخرین follower که پست به او تحویل شده
+	PublishedAt    *time.Time `gorm:"index"` // outbox: زمان انتشار در Redis stream
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	ProcessedAt    *time.Time `gorm:"index"`
+	DeletedAt      *time.Time `gorm:"index"`
 }
@@ -1,16 +1,16 @@
 package follower
 
 import (
+	"github.com/gofrs/uuid"
 	"time"
 	"virast/internal/core/user"
-	"github.com/gofrs/uuid"
 )
 
 type Follower struct {
 	ID         uuid.UUID  `gorm:"primary_key;type:char(36);default:uuid()"`
-	UserID     uuid.UUID  `gorm:"type:char(36);not null"`
+	UserID     uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_followers_user_follower_unique,priority:1"`
 	User       user.User  `gorm:"foreignkey:UserID"` // ارتباط با مدل User
-	FollowerID uuid.UUID  `gorm:"type:char(36);not null"`
+	FollowerID uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_followers_user_follower_unique,priority:2"`
 	Follower   user.User  `gorm:"foreignkey:FollowerID"` // ارتباط با مدل Follower
 	CreatedAt  time.Time  `gorm:"autoCreateTime"`
 	DeletedAt  *time.Time `gorm:"index"`
@@ -3,19 +3,38 @@ package followerapp
 import (
 	"context"
 	"errors"
+	"log"
+	"virast/internal/core/fanoutqueue"
 	followerEntity "virast/internal/core/follower"
+	eventsPort "virast/internal/ports/events"
+	fanoutPort "virast/internal/ports/fanoutqueue"
 	followerPort "virast/internal/ports/follower"
+	uowPort "virast/internal/ports/uow"
 
 	"github.com/gofrs/uuid"
 )
 
 type FollowerService struct {
 	FollowerRepository followerPort.FollowerRepository
+	Events             eventsPort.Publisher        // اطلاع‌رسانی follow به کاربر دنبال‌شده
+	FanoutRepository   fanoutPort.FanoutRepository // کارهای تایم‌لاین بعد از follow و آنفالو
+	FanoutQueue        fanoutPort.FanoutQueue      // صف Redis Streams برای FanoutWorker
+	UnitOfWork         uowPort.UnitOfWork          // ثبت/حذف follow و کار تایم‌لاین در یک تراکنش
 }
 
-func NewFollowerService(repo followerPort.FollowerRepository) *FollowerService {
+func NewFollowerService(
+	repo followerPort.FollowerRepository,
+	events eventsPort.Publisher,
+	fanoutRepo fanoutPort.FanoutRepository,
+	fanoutQueue fanoutPort.FanoutQueue,
+	unitOfWork uowPort.UnitOfWork,
+) *FollowerService {
 	return &FollowerService{
 		FollowerRepository: repo,
+		Events:             events,
+		FanoutRepository:   fanoutRepo,
+		FanoutQueue:        fanoutQueue,
+		UnitOfWork:         unitOfWork,
 	}
 }
 
@@ -30,12 +49,88 @@ func (s *FollowerService) FollowUser(ctx context.Context, followerID, followeeID
 		FollowerID: uuid.FromStringOrNil(followerID),
 	}
 
-	_, err := s.FollowerRepository.FollowUser(ctx, f)
-	return err
+	// ثبت follow و کار backfill پست‌های قبلی followee در یک تراکنش
+	var job *fanoutqueue.FanoutQueue
+	err := s.UnitOfWork.Do(ctx, func(repos uowPort.Repositories) error {
+		if _, err := repos.Followers.FollowUser(ctx, f); err != nil {
+			return err
+		}
+
+		var err error
+		job, err = repos.Fanouts.Create(ctx, &fanoutqueue.FanoutQueue{
+			ID:           uuid.Must(uuid.NewV4()),
+			Kind:         fanoutqueue.KindBackfill,
+			UserID:       f.UserID,
+			TargetUserID: &f.FollowerID,
+			Status:       fanoutqueue.StatusPending,
+		})
+		return err
+	})
+	if errors.Is(err, followerPort.ErrAlreadyFollowing) {
+		// درخواست تکراری؛ follow، شمارنده و کار backfill قبلاً ثبت شده‌اند
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(ctx, job)
+
+	// اطلاع‌رسانی زنده؛ خطا فقط لاگ می‌شود چون follow ثبت شده است
+	ev, err := eventsPort.NewEvent(eventsPort.TypeFollow, "", &eventsPort.FollowEventDTO{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err == nil {
+		err = s.Events.Publish(ctx, []string{followeeID}, ev)
+	}
+	if err != nil {
+		log.Println("Warning: could not publish follow event:", err)
+	}
+	return nil
 }
 
+// UnfollowUser حذف follow و ثبت کار پاک‌سازی پست‌های followee از تایم‌لاین follower در همان تراکنش؛
+// اگر follow وجود نداشته باشد (آنفالو تکراری) کاری ثبت نمی‌شود
 func (s *FollowerService) UnfollowUser(ctx context.Context, followerID, followeeID string) error {
-	return s.FollowerRepository.UnfollowUser(ctx, followerID, followeeID)
+	targetID := uuid.FromStringOrNil(followerID)
+
+	var job *fanoutqueue.FanoutQueue
+	err := s.UnitOfWork.Do(ctx, func(repos uowPort.Repositories) error {
+		if err := repos.Followers.UnfollowUser(ctx, followerID, followeeID); err != nil {
+			return err
+		}
+
+		var err error
+		job, err = repos.Fanouts.Create(ctx, &fanoutqueue.FanoutQueue{
+			ID:           uuid.Must(uuid.NewV4()),
+			Kind:         fanoutqueue.KindUnfollow,
+			UserID:       uuid.FromStringOrNil(followeeID),
+			TargetUserID: &targetID,
+			Status:       fanoutqueue.StatusPending,
+		})
+		return err
+	})
+	if errors.Is(err, followerPort.ErrNotFollowing) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.enqueue(ctx, job)
+	return nil
+}
+
+// enqueue انتشار فوری کار در stream؛ اگر نشد OutboxRelay بعداً منتشرش می‌کند
+func (s *FollowerService) enqueue(ctx context.Context, job *fanoutqueue.FanoutQueue) {
+	if err := s.FanoutQueue.Enqueue(ctx, fanoutPort.NewFanoutMessage(job)); err != nil {
+		log.Println("Warning: could not enqueue fanout job, leaving it to the outbox relay:", err)
+		return
+	}
+	if err := s.FanoutRepository.MarkPublished(ctx, job.ID); err != nil {
+		log.Println("Warning: could not mark fanout_queue published:", err)
+	}
 }
 
 func (s *FollowerService) GetFollowersByUserID(ctx context.Context, userID string) ([]*followerPort.FollowerDTO, error) {
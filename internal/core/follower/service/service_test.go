@@ -0,0 +1,160 @@
+package followerapp
+
+import (
+	"context"
+	"testing"
+	"virast/internal/core/fanoutqueue"
+	"virast/internal/core/follower"
+	eventsPort "virast/internal/ports/events"
+	fanoutPort "virast/internal/ports/fanoutqueue"
+	followerPort "virast/internal/ports/follower"
+	uowPort "virast/internal/ports/uow"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakeFollowerRepository مثل جدول followers؛ کلید follow ترکیب followerID و followeeID است
+type fakeFollowerRepository struct {
+	followerPort.FollowerRepository
+	follows map[string]bool
+}
+
+func (r *fakeFollowerRepository) FollowUser(ctx context.Context, f *follower.Follower) (*follower.Follower, error) {
+	key := f.FollowerID.String() + ":" + f.UserID.String()
+	if r.follows[key] {
+		return nil, followerPort.ErrAlreadyFollowing
+	}
+	r.follows[key] = true
+	return f, nil
+}
+
+func (r *fakeFollowerRepository) UnfollowUser(ctx context.Context, followerID, followeeID string) error {
+	key := followerID + ":" + followeeID
+	if !r.follows[key] {
+		return followerPort.ErrNotFollowing
+	}
+	delete(r.follows, key)
+	return nil
+}
+
+type fakeFanoutRepository struct {
+	fanoutPort.FanoutRepository
+	created []*fanoutqueue.FanoutQueue
+}
+
+func (r *fakeFanoutRepository) Create(ctx context.Context, fq *fanoutqueue.FanoutQueue) (*fanoutqueue.FanoutQueue, error) {
+	r.created = append(r.created, fq)
+	return fq, nil
+}
+
+func (r *fakeFanoutRepository) MarkPublished(ctx context.Context, ids ...uuid.UUID) error {
+	return nil
+}
+
+type fakeFanoutQueue struct {
+	fanoutPort.FanoutQueue
+	enqueued int
+}
+
+func (q *fakeFanoutQueue) Enqueue(ctx context.Context, msg *fanoutPort.FanoutMessage) error {
+	q.enqueued++
+	return nil
+}
+
+type fakePublisher struct{ published int }
+
+func (p *fakePublisher) Publish(ctx context.Context, userIDs []string, ev *eventsPort.Event) error {
+	p.published++
+	return nil
+}
+
+// fakeUnitOfWork fn را بدون تراکنش با همان ریپازیتوری‌ها اجرا می‌کند
+type fakeUnitOfWork struct {
+	repos uowPort.Repositories
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(repos uowPort.Repositories) error) error {
+	return fn(u.repos)
+}
+
+func newTestService(follows map[string]bool) (*FollowerService, *fakeFanoutRepository, *fakeFanoutQueue, *fakePublisher) {
+	followers := &fakeFollowerRepository{follows: follows}
+	fanouts := &fakeFanoutRepository{}
+	queue := &fakeFanoutQueue{}
+	events := &fakePublisher{}
+	uow := &fakeUnitOfWork{repos: uowPort.Repositories{Followers: followers, Fanouts: fanouts}}
+	return NewFollowerService(followers, events, fanouts, queue, uow), fanouts, queue, events
+}
+
+func TestFollowUser(t *testing.T) {
+	followerID := uuid.Must(uuid.NewV4()).String()
+	followeeID := uuid.Must(uuid.NewV4()).String()
+
+	tests := []struct {
+		name       string
+		following  bool
+		wantJobs   int
+		wantEvents int
+	}{
+		{"new follow records backfill job", false, 1, 1},
+		{"duplicate follow is a no-op", true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, fanouts, queue, events := newTestService(map[string]bool{followerID + ":" + followeeID: tt.following})
+
+			if err := svc.FollowUser(context.Background(), followerID, followeeID); err != nil {
+				t.Fatalf("FollowUser: %v", err)
+			}
+			if len(fanouts.created) != tt.wantJobs || queue.enqueued != tt.wantJobs {
+				t.Fatalf("jobs created = %d, enqueued = %d, want %d", len(fanouts.created), queue.enqueued, tt.wantJobs)
+			}
+			if tt.wantJobs > 0 && fanouts.created[0].Kind != fanoutqueue.KindBackfill {
+				t.Errorf("job kind = %q, want %q", fanouts.created[0].Kind, fanoutqueue.KindBackfill)
+			}
+			if events.published != tt.wantEvents {
+				t.Errorf("follow events = %d, want %d", events.published, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestUnfollowUser(t *testing.T) {
+	followerID := uuid.Must(uuid.NewV4()).String()
+	followeeID := uuid.Must(uuid.NewV4()).String()
+
+	tests := []struct {
+		name      string
+		following bool
+		wantJobs  int
+	}{
+		{"following records cleanup job", true, 1},
+		{"not following records nothing", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, fanouts, queue, _ := newTestService(map[string]bool{followerID + ":" + followeeID: tt.following})
+
+			if err := svc.UnfollowUser(context.Background(), followerID, followeeID); err != nil {
+				t.Fatalf("UnfollowUser: %v", err)
+			}
+			if len(fanouts.created) != tt.wantJobs || queue.enqueued != tt.wantJobs {
+				t.Fatalf("jobs created = %d, enqueued = %d, want %d", len(fanouts.created), queue.enqueued, tt.wantJobs)
+			}
+			if tt.wantJobs > 0 && fanouts.created[0].Kind != fanoutqueue.KindUnfollow {
+				t.Errorf("job kind = %q, want %q", fanouts.created[0].Kind, fanoutqueue.KindUnfollow)
+			}
+		})
+	}
+
+	// آنفالو دوم بعد از آنفالو موفق هم کاری ثبت نمی‌کند
+	svc, fanouts, _, _ := newTestService(map[string]bool{followerID + ":" + followeeID: true})
+	for i := 0; i < 2; i++ {
+		if err := svc.UnfollowUser(context.Background(), followerID, followeeID); err != nil {
+			t.Fatalf("UnfollowUser #%d: %v", i+1, err)
+		}
+	}
+	if len(fanouts.created) != 1 {
+		t.Errorf("jobs after repeated unfollow = %d, want 1", len(fanouts.created))
+	}
+}
@@ -0,0 +1,23 @@
+package notificationapp
+
+import (
+	"context"
+	eventsPort "virast/internal/ports/events"
+)
+
+// NotificationService دسترسی اتصال‌های زنده (WebSocket) به همه‌ی رویدادهای کاربر:
+// پست‌های جدید تایم‌لاین، follow ها و اعلان‌های بعدی
+type NotificationService struct {
+	Events eventsPort.Subscriber
+}
+
+func NewNotificationService(events eventsPort.Subscriber) *NotificationService {
+	return &NotificationService{
+		Events: events,
+	}
+}
+
+// Subscribe دریافت رویدادهای کاربر تا لغو ctx
+func (s *NotificationService) Subscribe(ctx context.Context, userID string) (<-chan *eventsPort.Event, error) {
+	return s.Events.Subscribe(ctx, userID)
+}
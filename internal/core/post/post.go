@@ -13,5 +13,21 @@ type Post struct {
 	User      user.User  `gorm:"foreignkey:UserID"` // ارتباط با مدل User
 	CreatedAt time.Time  `gorm:"autoCreateTime"`
 	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+	EditedAt  *time.Time // زمان آخرین ویرایش محتوا؛ nil یعنی ویرایش نشده
 	DeletedAt *time.Time `gorm:"index"`
+
+	ReplyToID  *uuid.UUID `gorm:"type:char(36);index"` // پستی که این پست پاسخ به آن است
+	RootID     *uuid.UUID `gorm:"type:char(36);index"` // اولین پست گفتگو؛ برای پست‌های غیر پاسخ nil
+	ReplyCount int64      `gorm:"not null;default:0"`  // تعداد پاسخ‌های مستقیم
+
+	RepostOfID   *uuid.UUID `gorm:"type:char(36);index"` // پست اصلی؛ repost محتوای خودش را ندارد
+	QuotedPostID *uuid.UUID `gorm:"type:char(36);index"` // پستی که همراه با Content نقل‌قول شده است
+}
+
+// ReferencedID شناسه‌ی پستی که این پست آن را repost یا نقل‌قول کرده است
+func (p *Post) ReferencedID() *uuid.UUID {
+	if p.RepostOfID != nil {
+		return p.RepostOfID
+	}
+	return p.QuotedPostID
 }
@@ -0,0 +1,16 @@
+package post
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// PostRevision نسخه‌ی قبلی محتوای یک پست که با ویرایش جایگزین شده است
+type PostRevision struct {
+	ID          uuid.UUID `gorm:"primary_key;type:char(36);default:uuid()"`
+	PostID      uuid.UUID `gorm:"type:char(36);not null;index"`
+	Content     string    `gorm:"type:text;not null"`
+	PublishedAt time.Time `gorm:"not null"`       // زمانی که این نسخه منتشر شده بود (ساخت پست یا ویرایش قبلی)
+	CreatedAt   time.Time `gorm:"autoCreateTime"` // زمان جایگزین شدن این نسخه
+}
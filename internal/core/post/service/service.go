@@ -2,17 +2,23 @@ package postapp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	//fanoutQueueEntity "virast/internal/core/fanoutqueue"
 	"virast/internal/core/fanoutqueue"
 	postEntity "virast/internal/core/post"
+	userEntity "virast/internal/core/user"
 
-	//"virast/internal/core/timeline"
+	"virast/internal/core/timeline"
+	eventsPort "virast/internal/ports/events"
 	fanoutPort "virast/internal/ports/fanoutqueue"
 	followerPort "virast/internal/ports/follower"
 	postPort "virast/internal/ports/post"
 	timelinePort "virast/internal/ports/timeline"
+	uowPort "virast/internal/ports/uow"
+	userPort "virast/internal/ports/user"
 
 	"github.com/gofrs/uuid"
 )
@@ -20,24 +26,50 @@ import (
 type PostService struct {
 	PostRepository     postPort.PostRepository
 	FanoutRepository   fanoutPort.FanoutRepository     // تزریق شده
+	FanoutQueue        fanoutPort.FanoutQueue          // صف Redis Streams برای FanoutWorker
 	FanoutRedis        fanoutPort.FanoutRedis          // تزریق شده
 	FollowerRepository followerPort.FollowerRepository // برای گرفتن followers
 	TimelineRepository timelinePort.TimelineRepository // برای ذخیره در جدول timeline
+	UnitOfWork         uowPort.UnitOfWork              // ثبت پست و رکورد outbox در یک تراکنش
+	PostCache          postPort.PostCache              // پاک کردن cache پست بعد از حذف
+	EditWindow         time.Duration                   // مهلت ویرایش پست بعد از انتشار
+	UserRepository     userPort.UserRepository         // برای نمایش نویسنده و صفحه‌ی پروفایل
+	Events             eventsPort.Publisher            // اطلاع‌رسانی زنده‌ی پست جدید به تایم‌لاین خود نویسنده
 }
 
+const (
+	defaultPageLimit = 20  // تعداد پیش‌فرض پست‌های هر صفحه
+	maxPageLimit     = 100 // حداکثر تعداد پست‌های هر صفحه
+
+	threadDepth       = 2 // تعداد سطح‌های پاسخ که در یک درخواست thread برگردانده می‌شوند
+	threadNestedLimit = 3 // تعداد پاسخ‌های سطح‌های پایین‌تر؛ ادامه‌ی آن‌ها با next_cursor همان پاسخ خوانده می‌شود
+)
+
 func NewPostService(
 	postRepo postPort.PostRepository,
 	fanoutRepo fanoutPort.FanoutRepository,
+	fanoutQueue fanoutPort.FanoutQueue,
 	fanoutRedis fanoutPort.FanoutRedis,
 	followerRepo followerPort.FollowerRepository,
 	timelineRepo timelinePort.TimelineRepository,
+	unitOfWork uowPort.UnitOfWork,
+	postCache postPort.PostCache,
+	editWindow time.Duration,
+	userRepo userPort.UserRepository,
+	events eventsPort.Publisher,
 ) *PostService {
 	return &PostService{
 		FollowerRepository: followerRepo,
 		FanoutRepository:   fanoutRepo,
+		FanoutQueue:        fanoutQueue,
 		FanoutRedis:        fanoutRedis,
 		PostRepository:     postRepo,
 		TimelineRepository: timelineRepo,
+		UnitOfWork:         unitOfWork,
+		PostCache:          postCache,
+		EditWindow:         editWindow,
+		UserRepository:     userRepo,
+		Events:             events,
 	}
 }
 
@@ -59,38 +91,547 @@ func (s *PostService) CreatePost(ctx context.Context, content, userID string) (*
 		UserID:  uid,
 	}
 
-	createdPost, err := s.PostRepository.Create(post)
+	createdPost, err := s.createPost(ctx, post)
 	if err != nil {
 		fmt.Println("❌ Failed to create post for userID:", userID, "error:", err)
-		return nil, fmt.Errorf("failed to create post: %w", err)
+		return nil, err
+	}
+
+	fmt.Println("🚀 CreatePost completed for postID:", createdPost.ID)
+	return &postPort.PostDTO{
+		ID:      createdPost.ID.String(),
+		Content: createdPost.Content,
+	}, nil
+}
+
+// CreateReply ثبت پاسخ به پست parentID؛ پاسخ مثل هر پست دیگری fanout می‌شود
+// ولی FanoutWorker مخاطبان آن را طبق REPLY_FANOUT محدود می‌کند
+func (s *PostService) CreateReply(ctx context.Context, parentID, content, userID string) (*postPort.PostDTO, error) {
+	uid, err := uuid.FromString(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID: %w", err)
 	}
-	fmt.Println("✅ Created post:", createdPost.ID, "for user:", createdPost.UserID)
 
-	// 2️⃣ ایجاد رکورد FanoutQueue (pending)
-	fq := &fanoutqueue.FanoutQueue{
-		ID:     uuid.Must(uuid.NewV4()),
-		PostID: createdPost.ID,
-		UserID: createdPost.UserID,
-		Status: "pending",
+	parent, err := s.findOriginal(parentID)
+	if err != nil {
+		return nil, err
 	}
+	parentID = parent.ID.String()
 
-	fanoutRecord, err := s.FanoutRepository.Create(ctx, fq)
+	// همه‌ی پاسخ‌های یک گفتگو به اولین پست آن اشاره می‌کنند
+	rootID := parent.ID
+	if parent.RootID != nil {
+		rootID = *parent.RootID
+	}
+
+	reply, err := s.createPost(ctx, &postEntity.Post{
+		ID:        uuid.Must(uuid.NewV4()),
+		Content:   content,
+		UserID:    uid,
+		ReplyToID: &parent.ID,
+		RootID:    &rootID,
+	})
 	if err != nil {
-		fmt.Println("⚠️ Warning: could not add to fanout_queue:", err)
-	} else {
-		fmt.Println("✅ FanoutQueue record created:", fanoutRecord.ID)
+		fmt.Println("❌ Failed to create reply to post:", parentID, "error:", err)
+		return nil, err
+	}
+
+	// reply_count پست والد تغییر کرده است
+	if err := s.PostCache.Invalidate(ctx, parentID); err != nil {
+		fmt.Println("⚠️ Warning: could not invalidate cached post:", err)
+	}
+
+	fmt.Println("💬 Created reply:", reply.ID, "to post:", parentID)
+	return postPort.NewPostDTO(reply), nil
+}
+
+// Repost انتشار دوباره‌ی پست postID برای followers کاربر؛ repost یک پست بدون محتوا با RepostOfID است
+// و مثل هر پست دیگری توسط FanoutWorker تحویل داده می‌شود. repost یک repost به پست اصلی اشاره می‌کند
+func (s *PostService) Repost(ctx context.Context, postID, userID string) (*postPort.PostDTO, error) {
+	uid, err := uuid.FromString(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID: %w", err)
+	}
+
+	orig, err := s.findOriginal(postID)
+	if err != nil {
+		return nil, err
+	}
+	// بررسی سریع؛ درخواست‌های همزمان را unique index پایگاه داده با ErrAlreadyReposted رد می‌کند
+	if _, err := s.PostRepository.FindRepost(userID, orig.ID.String()); err == nil {
+		return nil, postPort.ErrAlreadyReposted
+	} else if !errors.Is(err, postPort.ErrPostNotFound) {
+		return nil, err
+	}
+
+	repost, err := s.createPost(ctx, &postEntity.Post{
+		ID:         uuid.Must(uuid.NewV4()),
+		UserID:     uid,
+		RepostOfID: &orig.ID,
+	})
+	if errors.Is(err, postPort.ErrAlreadyReposted) {
+		return nil, postPort.ErrAlreadyReposted
+	}
+	if err != nil {
+		fmt.Println("❌ Failed to repost post:", orig.ID, "error:", err)
+		return nil, err
+	}
+
+	fmt.Println("🔁 Created repost:", repost.ID, "of post:", orig.ID)
+	return s.GetPost(ctx, repost.ID.String())
+}
+
+// QuotePost انتشار پست جدید با محتوای content که پست postID را نقل‌قول می‌کند
+func (s *PostService) QuotePost(ctx context.Context, postID, content, userID string) (*postPort.PostDTO, error) {
+	uid, err := uuid.FromString(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID: %w", err)
+	}
+
+	quoted, err := s.findOriginal(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.createPost(ctx, &postEntity.Post{
+		ID:           uuid.Must(uuid.NewV4()),
+		Content:      content,
+		UserID:       uid,
+		QuotedPostID: &quoted.ID,
+	})
+	if err != nil {
+		fmt.Println("❌ Failed to quote post:", quoted.ID, "error:", err)
+		return nil, err
+	}
+
+	fmt.Println("💬 Created quote:", quote.ID, "of post:", quoted.ID)
+	return s.GetPost(ctx, quote.ID.String())
+}
+
+// findOriginal پست حذف‌نشده‌ی postID؛ برای repost ها پست اصلی برگردانده می‌شود
+func (s *PostService) findOriginal(postID string) (*postEntity.Post, error) {
+	p, err := s.PostRepository.FindByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if p.RepostOfID != nil {
+		if p.DeletedAt != nil {
+			return nil, postPort.ErrPostNotFound
+		}
+		p, err = s.PostRepository.FindByID(p.RepostOfID.String())
+		if err != nil {
+			return nil, err
+		}
 	}
+	if p.DeletedAt != nil {
+		return nil, postPort.ErrPostNotFound
+	}
+	return p, nil
+}
+
+// createPost ثبت پست، رکورد outbox و ردیف timeline نویسنده در یک تراکنش و سپس انتشار پیام fanout
+func (s *PostService) createPost(ctx context.Context, post *postEntity.Post) (*postEntity.Post, error) {
+	// ستون created_at از نوع datetime(3) است و MySQL کسر ثانیه را گرد می‌کند؛ با کوتاه کردن زمان به میلی‌ثانیه
+	// امتیاز ZSET نویسنده، followers و خواندن از MySQL همه از یک مقدار ساخته می‌شوند
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now().Truncate(time.Millisecond)
+		post.UpdatedAt = post.CreatedAt
+	}
+
+	// 2️⃣ ایجاد رکورد FanoutQueue (pending) به عنوان outbox در همان تراکنش
+	var createdPost *postEntity.Post
+	var fanoutRecord *fanoutqueue.FanoutQueue
+	err := s.UnitOfWork.Do(ctx, func(repos uowPort.Repositories) error {
+		var err error
+		createdPost, err = repos.Posts.Create(post)
+		if err != nil {
+			return fmt.Errorf("failed to create post: %w", err)
+		}
+
+		if post.ReplyToID != nil {
+			if err := repos.Posts.AddReplyCount(post.ReplyToID.String(), 1); err != nil {
+				return fmt.Errorf("failed to update reply count: %w", err)
+			}
+		}
+
+		fq := &fanoutqueue.FanoutQueue{
+			ID:     uuid.Must(uuid.NewV4()),
+			Kind:   fanoutqueue.KindPost,
+			PostID: &createdPost.ID,
+			UserID: createdPost.UserID,
+			Status: fanoutqueue.StatusPending,
+		}
+		fanoutRecord, err = repos.Fanouts.Create(ctx, fq)
+		if err != nil {
+			return fmt.Errorf("failed to add to fanout_queue: %w", err)
+		}
+
+		// پست در جدول timeline خود نویسنده هم ثبت می‌شود تا بعد از trim شدن ZSET از MySQL خوانده شود
+		return repos.Timelines.Add(ctx, &timeline.Timeline{
+			UserID:    createdPost.UserID,
+			PostID:    createdPost.ID,
+			CreatedAt: createdPost.CreatedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("✅ Created post:", createdPost.ID, "with FanoutQueue record:", fanoutRecord.ID)
+
+	s.enqueue(ctx, fanoutRecord)
 
 	// 3️⃣ پیام برای FanoutWorker (برای ZSET)
-	if err := s.FanoutRedis.PushPostToFollowers(ctx, createdPost.ID.String(), []string{createdPost.UserID.String()}); err != nil {
+	if err := s.FanoutRedis.PushPostToFollowers(ctx, createdPost.ID.String(), createdPost.CreatedAt, []string{createdPost.UserID.String()}); err != nil {
 		fmt.Println("⚠️ Warning: could not push post to Redis ZSET:", err)
 	} else {
 		fmt.Println("✅ Post pushed to Redis ZSET for user:", createdPost.UserID)
 	}
 
-	fmt.Println("🚀 CreatePost completed for postID:", createdPost.ID)
-	return &postPort.PostDTO{
-		ID:      createdPost.ID.String(),
-		Content: createdPost.Content,
-	}, nil
+	// پست در تایم‌لاین خود نویسنده است، پس stream های باز او (مثلاً تب دیگر) هم آن را دریافت می‌کنند؛
+	// FanoutWorker فقط برای followers منتشر می‌کند
+	s.publishToAuthor(ctx, createdPost)
+	return createdPost, nil
+}
+
+// publishToAuthor انتشار رویداد تایم‌لاین پست برای نویسنده‌ی آن؛ خطا فقط لاگ می‌شود چون پست ثبت شده است
+func (s *PostService) publishToAuthor(ctx context.Context, p *postEntity.Post) {
+	lookup, err := postPort.LoadPostLookup(s.PostRepository, s.UserRepository, []*postEntity.Post{p})
+	if err != nil {
+		fmt.Println("⚠️ Warning: could not load post author for event:", err)
+		lookup = nil
+	}
+	ev, err := postPort.NewTimelineEvent(lookup, p)
+	if err == nil {
+		err = s.Events.Publish(ctx, []string{p.UserID.String()}, ev)
+	}
+	if err != nil {
+		fmt.Println("⚠️ Warning: could not publish timeline event to author:", err)
+	}
+}
+
+// DeletePost حذف نرم پست توسط نویسنده و ثبت کار retract برای حذف آن از تایم‌لاین followers
+func (s *PostService) DeletePost(ctx context.Context, postID, userID string) error {
+	post, err := s.PostRepository.FindByID(postID)
+	if err != nil {
+		return err
+	}
+	if post.DeletedAt != nil {
+		return postPort.ErrPostNotFound
+	}
+	if post.UserID.String() != userID {
+		return postPort.ErrNotPostAuthor
+	}
+
+	// حذف پست و رکورد outbox در یک تراکنش تا retract گم نشود
+	var fanoutRecord *fanoutqueue.FanoutQueue
+	err = s.UnitOfWork.Do(ctx, func(repos uowPort.Repositories) error {
+		if err := repos.Posts.Delete(postID); err != nil {
+			return err
+		}
+		if post.ReplyToID != nil {
+			if err := repos.Posts.AddReplyCount(post.ReplyToID.String(), -1); err != nil {
+				return fmt.Errorf("failed to update reply count: %w", err)
+			}
+		}
+
+		var err error
+		fanoutRecord, err = repos.Fanouts.Create(ctx, &fanoutqueue.FanoutQueue{
+			ID:     uuid.Must(uuid.NewV4()),
+			Kind:   fanoutqueue.KindRetract,
+			PostID: &post.ID,
+			UserID: post.UserID,
+			Status: fanoutqueue.StatusPending,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add to fanout_queue: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// تا پاک شدن ZSET ها، hydrate تایم‌لاین پست را از MySQL حذف‌شده می‌بیند و نمایش نمی‌دهد
+	invalidate := []string{postID}
+	if post.ReplyToID != nil {
+		invalidate = append(invalidate, post.ReplyToID.String())
+	}
+	if err := s.PostCache.Invalidate(ctx, invalidate...); err != nil {
+		fmt.Println("⚠️ Warning: could not invalidate cached post:", err)
+	}
+	s.enqueue(ctx, fanoutRecord)
+
+	fmt.Println("🗑️ Deleted post:", postID)
+	return nil
+}
+
+// EditPost ویرایش محتوای پست توسط نویسنده تا EditWindow بعد از انتشار؛ محتوای قبلی در post_revisions ذخیره می‌شود.
+// پست داخل تراکنش با FOR UPDATE خوانده می‌شود تا ویرایش‌های همزمان پشت سر هم اجرا شوند و هیچ نسخه‌ای از تاریخچه جا نیفتد.
+// تایم‌لاین‌ها فقط شناسه‌ی پست را نگه می‌دارند، پس بعد از باطل شدن cache نسخه‌ی جدید نمایش داده می‌شود
+func (s *PostService) EditPost(ctx context.Context, postID, userID, content string) (*postPort.PostDTO, error) {
+	var post *postEntity.Post
+	edited := false
+	err := s.UnitOfWork.Do(ctx, func(repos uowPort.Repositories) error {
+		var err error
+		post, err = repos.Posts.FindByIDForUpdate(postID)
+		if err != nil {
+			return err
+		}
+		if post.DeletedAt != nil {
+			return postPort.ErrPostNotFound
+		}
+		if post.UserID.String() != userID {
+			return postPort.ErrNotPostAuthor
+		}
+		if post.RepostOfID != nil {
+			return postPort.ErrRepostNotEditable
+		}
+		if time.Since(post.CreatedAt) > s.EditWindow {
+			return postPort.ErrEditWindowClosed
+		}
+		if content == post.Content {
+			return nil
+		}
+
+		// نسخه‌ی قبلی از زمان ساخت یا آخرین ویرایش منتشر بوده است
+		publishedAt := post.CreatedAt
+		if post.EditedAt != nil {
+			publishedAt = *post.EditedAt
+		}
+		if err := repos.Posts.AddRevision(&postEntity.PostRevision{
+			ID:          uuid.Must(uuid.NewV4()),
+			PostID:      post.ID,
+			Content:     post.Content,
+			PublishedAt: publishedAt,
+		}); err != nil {
+			return fmt.Errorf("failed to save post revision: %w", err)
+		}
+
+		now := time.Now()
+		post.Content = content
+		post.EditedAt = &now
+		edited = true
+		return repos.Posts.Update(post)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !edited {
+		return postPort.NewPostDTO(post), nil
+	}
+
+	if err := s.PostCache.Invalidate(ctx, postID); err != nil {
+		fmt.Println("⚠️ Warning: could not invalidate cached post:", err)
+	}
+
+	fmt.Println("✏️ Edited post:", postID)
+	return postPort.NewPostDTO(post), nil
+}
+
+// GetPostHistory محتوای فعلی پست و نسخه‌های قبلی آن
+func (s *PostService) GetPostHistory(ctx context.Context, postID string) (*postPort.PostHistoryDTO, error) {
+	post, err := s.PostRepository.FindByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.DeletedAt != nil {
+		return nil, postPort.ErrPostNotFound
+	}
+
+	revs, err := s.PostRepository.FindRevisions(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &postPort.PostHistoryDTO{
+		Post:      postPort.NewPostDTO(post),
+		Revisions: make([]*postPort.PostRevisionDTO, 0, len(revs)),
+	}
+	for _, r := range revs {
+		history.Revisions = append(history.Revisions, &postPort.PostRevisionDTO{
+			Content:     r.Content,
+			PublishedAt: r.PublishedAt.String(),
+			ReplacedAt:  r.CreatedAt.String(),
+		})
+	}
+	return history, nil
+}
+
+// GetPost یک پست با اطلاعات نویسنده؛ پست حذف‌شده یا پست کاربر حذف‌شده پیدا نمی‌شود
+func (s *PostService) GetPost(ctx context.Context, postID string) (*postPort.PostDTO, error) {
+	post, err := s.PostRepository.FindByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.DeletedAt != nil {
+		return nil, postPort.ErrPostNotFound
+	}
+
+	lookup, err := postPort.LoadPostLookup(s.PostRepository, s.UserRepository, []*postEntity.Post{post})
+	if err != nil {
+		return nil, err
+	}
+	dto := lookup.DTO(post)
+	if dto.User == nil {
+		return nil, postPort.ErrPostNotFound
+	}
+	return dto, nil
+}
+
+// GetUserPosts صفحه‌ای از پست‌های کاربر username از جدید به قدیم؛ cursor خالی یعنی صفحه‌ی اول
+func (s *PostService) GetUserPosts(ctx context.Context, username, cursor string, limit int) (*postPort.UserPostsPage, error) {
+	var after *timeline.Cursor
+	if cursor != "" {
+		c, err := timeline.DecodeCursor(cursor)
+		if err != nil {
+			return nil, timelinePort.ErrInvalidCursor
+		}
+		after = c
+	}
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	author, err := s.UserRepository.FindByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if author.DeletedAt != nil {
+		return nil, userPort.ErrUserNotFound
+	}
+
+	posts, err := s.PostRepository.FindPageByUserID(author.ID.String(), after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup, err := postPort.LoadPostLookup(s.PostRepository, s.UserRepository, posts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &postPort.UserPostsPage{
+		User:  toUserDTO(author),
+		Posts: make([]*postPort.PostDTO, 0, len(posts)),
+	}
+	for _, p := range posts {
+		dto := lookup.DTO(p)
+		dto.Cursor = timeline.Cursor{Score: timeline.Score(p.CreatedAt), PostID: p.ID.String()}.Encode()
+		page.Posts = append(page.Posts, dto)
+	}
+	// صفحه‌ی کامل یعنی ممکن است پست دیگری باقی مانده باشد
+	if len(posts) == limit {
+		page.NextCursor = page.Posts[len(page.Posts)-1].Cursor
+	}
+	return page, nil
+}
+
+// GetThread پست postID و پاسخ‌های آن تا threadDepth سطح؛ cursor و limit فقط سطح اول را صفحه‌بندی می‌کنند
+// و هر پاسخ next_cursor سطح خودش را دارد
+func (s *PostService) GetThread(ctx context.Context, postID, cursor string, limit int) (*postPort.ThreadDTO, error) {
+	var after *timeline.Cursor
+	if cursor != "" {
+		c, err := timeline.DecodeCursor(cursor)
+		if err != nil {
+			return nil, timelinePort.ErrInvalidCursor
+		}
+		after = c
+	}
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	post, err := s.PostRepository.FindByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.DeletedAt != nil {
+		return nil, postPort.ErrPostNotFound
+	}
+
+	thread := &postPort.ThreadDTO{Post: postPort.NewPostDTO(post)}
+	posts := []*postEntity.Post{post}
+	if err := s.loadReplies(thread, post, after, limit, threadDepth, &posts); err != nil {
+		return nil, err
+	}
+
+	// نویسندگان و پست‌های نقل‌قول‌شده‌ی همه‌ی پست‌های thread با چند کوئری دسته‌ای
+	lookup, err := postPort.LoadPostLookup(s.PostRepository, s.UserRepository, posts)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := lookup.Authors[post.UserID.String()]; !ok {
+		return nil, postPort.ErrPostNotFound
+	}
+	fillThread(thread, lookup)
+	return thread, nil
+}
+
+// loadReplies خواندن پاسخ‌های مستقیم parent و به صورت بازگشتی پاسخ‌های آن‌ها تا depth سطح
+func (s *PostService) loadReplies(node *postPort.ThreadDTO, parent *postEntity.Post, after *timeline.Cursor, limit, depth int, posts *[]*postEntity.Post) error {
+	node.Replies = []*postPort.ThreadDTO{}
+	if depth == 0 || parent.ReplyCount == 0 {
+		return nil
+	}
+
+	replies, err := s.PostRepository.FindReplies(parent.ID.String(), after, limit)
+	if err != nil {
+		return err
+	}
+	for _, r := range replies {
+		child := &postPort.ThreadDTO{Post: postPort.NewPostDTO(r)}
+		child.Post.Cursor = timeline.Cursor{Score: timeline.Score(r.CreatedAt), PostID: r.ID.String()}.Encode()
+		if err := s.loadReplies(child, r, nil, threadNestedLimit, depth-1, posts); err != nil {
+			return err
+		}
+		node.Replies = append(node.Replies, child)
+		*posts = append(*posts, r)
+	}
+	// صفحه‌ی کامل یعنی ممکن است پاسخ دیگری باقی مانده باشد
+	if len(replies) == limit {
+		node.NextCursor = node.Replies[len(node.Replies)-1].Post.Cursor
+	}
+	return nil
+}
+
+// fillThread تکمیل پست‌های thread با نویسنده و پست نقل‌قول‌شده؛ پاسخ‌های کاربران حذف‌شده نمایش داده نمی‌شوند
+func fillThread(node *postPort.ThreadDTO, lookup *postPort.PostLookup) {
+	cursor := node.Post.Cursor
+	node.Post = lookup.DTO(lookup.Posts[node.Post.ID])
+	node.Post.Cursor = cursor
+
+	replies := node.Replies[:0]
+	for _, child := range node.Replies {
+		if _, ok := lookup.Authors[child.Post.UserID]; !ok {
+			continue
+		}
+		fillThread(child, lookup)
+		replies = append(replies, child)
+	}
+	node.Replies = replies
+}
+
+func toUserDTO(u *userEntity.User) *userPort.UserDTO {
+	return &userPort.UserDTO{
+		ID:       u.ID.String(),
+		Username: u.Username,
+		Mobile:   u.Mobile,
+	}
+}
+
+// enqueue انتشار فوری پیام در stream؛ اگر نشد OutboxRelay بعداً منتشرش می‌کند
+func (s *PostService) enqueue(ctx context.Context, fq *fanoutqueue.FanoutQueue) {
+	if err := s.FanoutQueue.Enqueue(ctx, fanoutPort.NewFanoutMessage(fq)); err != nil {
+		fmt.Println("⚠️ Warning: could not enqueue fanout message, leaving it to the outbox relay:", err)
+	} else if err := s.FanoutRepository.MarkPublished(ctx, fq.ID); err != nil {
+		fmt.Println("⚠️ Warning: could not mark fanout_queue published:", err)
+	} else {
+		fmt.Println("✅ Fanout message enqueued:", fq.ID, fq.Kind)
+	}
 }
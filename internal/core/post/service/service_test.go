@@ -0,0 +1,103 @@
+package postapp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	postEntity "virast/internal/core/post"
+	"virast/internal/core/timeline"
+	userEntity "virast/internal/core/user"
+	eventsPort "virast/internal/ports/events"
+	postPort "virast/internal/ports/post"
+	userPort "virast/internal/ports/user"
+
+	"github.com/gofrs/uuid"
+)
+
+type fakePostRepository struct {
+	postPort.PostRepository
+}
+
+func (r *fakePostRepository) FindByIDs(ids []string) ([]*postEntity.Post, error) {
+	return nil, nil
+}
+
+type fakeUserRepository struct {
+	userPort.UserRepository
+	users map[string]*userEntity.User
+}
+
+func (r *fakeUserRepository) FindByIDs(ids []string) ([]*userEntity.User, error) {
+	var found []*userEntity.User
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok {
+			found = append(found, u)
+		}
+	}
+	return found, nil
+}
+
+type publishedEvent struct {
+	userIDs []string
+	event   *eventsPort.Event
+}
+
+type fakePublisher struct{ published []publishedEvent }
+
+func (p *fakePublisher) Publish(ctx context.Context, userIDs []string, ev *eventsPort.Event) error {
+	p.published = append(p.published, publishedEvent{userIDs: userIDs, event: ev})
+	return nil
+}
+
+func TestPublishToAuthor(t *testing.T) {
+	author := &userEntity.User{ID: uuid.Must(uuid.NewV4()), Username: "author"}
+	createdAt := time.Now().Truncate(time.Millisecond)
+
+	tests := []struct {
+		name string
+		post *postEntity.Post
+	}{
+		{"post", &postEntity.Post{ID: uuid.Must(uuid.NewV4()), UserID: author.ID, Content: "hello", CreatedAt: createdAt}},
+		{"reply", &postEntity.Post{ID: uuid.Must(uuid.NewV4()), UserID: author.ID, Content: "hi", CreatedAt: createdAt, ReplyToID: uuidPtr()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := &fakePublisher{}
+			s := &PostService{
+				PostRepository: &fakePostRepository{},
+				UserRepository: &fakeUserRepository{users: map[string]*userEntity.User{author.ID.String(): author}},
+				Events:         events,
+			}
+
+			s.publishToAuthor(context.Background(), tt.post)
+
+			if len(events.published) != 1 {
+				t.Fatalf("published %d events, want 1", len(events.published))
+			}
+			got := events.published[0]
+			if len(got.userIDs) != 1 || got.userIDs[0] != author.ID.String() {
+				t.Errorf("recipients = %v, want [%s]", got.userIDs, author.ID)
+			}
+
+			// شناسه‌ی رویداد همان cursor تایم‌لاین است تا /timeline/new و Last-Event-ID با stream یکی باشند
+			wantCursor := timeline.Cursor{Score: timeline.Score(createdAt), PostID: tt.post.ID.String()}.Encode()
+			if got.event.Type != eventsPort.TypeTimelinePost || got.event.ID != wantCursor {
+				t.Errorf("event = %s/%s, want %s/%s", got.event.Type, got.event.ID, eventsPort.TypeTimelinePost, wantCursor)
+			}
+
+			var dto postPort.PostDTO
+			if err := json.Unmarshal(got.event.Data, &dto); err != nil {
+				t.Fatal(err)
+			}
+			if dto.User == nil || dto.User.Username != author.Username {
+				t.Errorf("event author = %+v, want %s", dto.User, author.Username)
+			}
+		})
+	}
+}
+
+func uuidPtr() *uuid.UUID {
+	id := uuid.Must(uuid.NewV4())
+	return &id
+}
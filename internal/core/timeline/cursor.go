@@ -0,0 +1,55 @@
+package timeline
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cursor موقعیت آخرین پست دیده‌شده در تایم‌لاین؛ صفحه‌ی بعد از پست‌های بعد از آن (امتیاز کمتر،
+// یا امتیاز برابر و شناسه‌ی کمتر) شروع می‌شود، پس پست‌های جدید بین دو درخواست باعث تکرار یا جا افتادن نمی‌شوند
+type Cursor struct {
+	Score  float64
+	PostID string
+}
+
+// Encode تبدیل cursor به رشته‌ی opaque برای کلاینت
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%s", int64(c.Score), c.PostID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// After آیا پستی با امتیاز score و شناسه‌ی postID در ترتیب تایم‌لاین بعد از cursor قرار دارد
+func (c Cursor) After(score float64, postID string) bool {
+	if score != c.Score {
+		return score < c.Score
+	}
+	return postID < c.PostID
+}
+
+// Newer آیا پستی با امتیاز score و شناسه‌ی postID در تایم‌لاین جدیدتر از cursor است
+func (c Cursor) Newer(score float64, postID string) bool {
+	if score != c.Score {
+		return score > c.Score
+	}
+	return postID > c.PostID
+}
+
+// DecodeCursor خواندن cursor ساخته‌شده با Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	score, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{Score: float64(score), PostID: parts[1]}, nil
+}
@@ -0,0 +1,79 @@
+package timeline
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"uuid post id", Cursor{Score: Score(time.UnixMilli(1700000000123)), PostID: "0b6f8a52-6a3e-4c1e-9d8e-1f2a3b4c5d6e"}},
+		{"zero score", Cursor{Score: 0, PostID: "a"}},
+		{"post id with colon", Cursor{Score: 42, PostID: "x:y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(tt.cursor.Encode())
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if *got != tt.cursor {
+				t.Errorf("round trip = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"not base64", "!!!"},
+		{"missing separator", raw("123")},
+		{"missing post id", raw("123:")},
+		{"non numeric score", raw("abc:post")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := DecodeCursor(tt.input); err == nil {
+				t.Errorf("DecodeCursor(%q) = %+v, want error", tt.input, c)
+			}
+		})
+	}
+}
+
+func TestCursorOrdering(t *testing.T) {
+	c := Cursor{Score: 100, PostID: "m"}
+
+	tests := []struct {
+		name      string
+		score     float64
+		postID    string
+		wantAfter bool
+		wantNewer bool
+	}{
+		{"older score", 99, "z", true, false},
+		{"newer score", 101, "a", false, true},
+		{"same score lower id", 100, "a", true, false},
+		{"same score higher id", 100, "z", false, true},
+		{"same post", 100, "m", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.After(tt.score, tt.postID); got != tt.wantAfter {
+				t.Errorf("After = %v, want %v", got, tt.wantAfter)
+			}
+			if got := c.Newer(tt.score, tt.postID); got != tt.wantNewer {
+				t.Errorf("Newer = %v, want %v", got, tt.wantNewer)
+			}
+		})
+	}
+}
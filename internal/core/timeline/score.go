@@ -0,0 +1,14 @@
+package timeline
+
+import "time"
+
+// Score امتیاز یک پست در ZSET تایم‌لاین: زمان ساخت پست به میلی‌ثانیه.
+// برای امتیازهای برابر ترتیب با شناسه‌ی پست تعیین می‌شود (مثل ترتیب lexicographic اعضا در ZREVRANGE)
+// پس ترتیب تایم‌لاین به تاخیر صف fanout وابسته نیست
+func Score(createdAt time.Time) float64 {
+	return float64(createdAt.UnixMilli())
+}
+
+// LegacyScoreLimit امتیازهای کمتر از این مقدار به ثانیه ثبت شده‌اند (قبل از امتیاز میلی‌ثانیه‌ای)؛
+// 1e11 به میلی‌ثانیه سال ۱۹۷۳ و به ثانیه سال ۵۱۳۸ است، پس دو نوع امتیاز با هم اشتباه نمی‌شوند
+const LegacyScoreLimit = 1e11
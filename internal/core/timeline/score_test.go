@@ -0,0 +1,28 @@
+package timeline
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLegacyScoreLimit(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		score float64
+		want  bool
+	}{
+		{"second based score", float64(createdAt.Unix()), true},
+		{"millisecond score", Score(createdAt), false},
+		{"epoch", 0, true},
+		{"far future seconds", float64(time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC).Unix()), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.score < LegacyScoreLimit; got != tt.want {
+				t.Fatalf("%v < LegacyScoreLimit = %v, want %v", tt.score, got, tt.want)
+			}
+		})
+	}
+}
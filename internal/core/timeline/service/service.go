@@ -2,26 +2,206 @@ package timelineapp
 
 import (
 	"context"
+	"log"
+	"math"
+	"time"
 	"virast/internal/core/timeline"
+	eventsPort "virast/internal/ports/events"
 	postPort "virast/internal/ports/post"
 	timelinePort "virast/internal/ports/timeline"
 )
 
+const (
+	replayLimit      = 100 // حداکثر تعداد پست‌هایی که بعد از اتصال دوباره‌ی stream با Last-Event-ID فرستاده می‌شوند
+	defaultPageLimit = 20  // تعداد پیش‌فرض پست‌های هر صفحه
+	maxPageLimit     = 100 // حداکثر تعداد پست‌های هر صفحه
+)
+
 type TimelineService struct {
 	TimelineRepository timelinePort.TimelineRepository
+	Events             eventsPort.Subscriber // رویدادهای زنده‌ی کاربر برای stream
 }
 
-func NewTimelineService(timelineRepo timelinePort.TimelineRepository) *TimelineService {
+func NewTimelineService(timelineRepo timelinePort.TimelineRepository, events eventsPort.Subscriber) *TimelineService {
 	return &TimelineService{
 		TimelineRepository: timelineRepo,
+		Events:             events,
 	}
 }
 
 // GetTimelineByUserID دریافت تایم‌لاین یک کاربر با استفاده از شناسه کاربری، شروع و محدودیت
 func (s *TimelineService) GetTimelineByUserID(ctx context.Context, userID string, start, limit int64) ([]*postPort.PostDTO, error) {
+	limit = pageLimit(limit)
+	// start+limit نباید سرریز کند؛ چنین offset ی به هر حال بعد از انتهای تایم‌لاین است
+	if start < 0 || start > math.MaxInt64-limit {
+		return []*postPort.PostDTO{}, nil
+	}
 	return s.TimelineRepository.GetTimelineByUserID(ctx, userID, start, limit)
 }
 
+// GetTimelinePage دریافت صفحه‌ی بعد از cursor؛ cursor خالی یعنی صفحه‌ی اول
+func (s *TimelineService) GetTimelinePage(ctx context.Context, userID, cursor string, limit int64) (*timelinePort.TimelinePage, error) {
+	var after *timeline.Cursor
+	if cursor != "" {
+		c, err := timeline.DecodeCursor(cursor)
+		if err != nil {
+			return nil, timelinePort.ErrInvalidCursor
+		}
+		after = c
+	}
+	return s.TimelineRepository.GetTimelinePage(ctx, userID, after, pageLimit(limit))
+}
+
+// GetNewPosts تعداد پست‌های جدیدتر از cursor since و در صورت withPosts جدیدترین limit پست از آن‌ها
+func (s *TimelineService) GetNewPosts(ctx context.Context, userID, since string, withPosts bool, limit int64) (*timelinePort.NewPostsDTO, error) {
+	c, err := timeline.DecodeCursor(since)
+	if err != nil {
+		return nil, timelinePort.ErrInvalidCursor
+	}
+
+	count, err := s.TimelineRepository.CountNewer(ctx, userID, c)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &timelinePort.NewPostsDTO{Count: count}
+	if withPosts && count > 0 {
+		res.Posts, err = s.TimelineRepository.GetNewerThan(ctx, userID, c, pageLimit(limit))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// StreamTimeline رویدادهای تایم‌لاین کاربر تا لغو ctx؛ اگر lastEventID داده شود ابتدا پست‌هایی
+// که بعد از آن اضافه شده‌اند (حداکثر replayLimit، از قدیمی به جدید) فرستاده می‌شوند
+func (s *TimelineService) StreamTimeline(ctx context.Context, userID, lastEventID string) (<-chan *eventsPort.Event, error) {
+	var since *timeline.Cursor
+	if lastEventID != "" {
+		c, err := timeline.DecodeCursor(lastEventID)
+		if err != nil {
+			return nil, timelinePort.ErrInvalidCursor
+		}
+		since = c
+	}
+
+	// اشتراک قبل از replay تا رویدادی بین این دو از دست نرود
+	live, err := s.Events.Subscribe(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var replay []*postPort.PostDTO
+	if since != nil {
+		replay, err = s.TimelineRepository.GetNewerThan(ctx, userID, since, replayLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make(chan *eventsPort.Event)
+	go func() {
+		defer close(out)
+
+		send := func(ev *eventsPort.Event) bool {
+			select {
+			case out <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		replayed := make(map[string]bool, len(replay))
+		for i := len(replay) - 1; i >= 0; i-- {
+			ev, err := eventsPort.NewEvent(eventsPort.TypeTimelinePost, replay[i].Cursor, replay[i])
+			if err != nil {
+				continue
+			}
+			replayed[ev.ID] = true
+			if !send(ev) {
+				return
+			}
+		}
+
+		// فقط رویدادهای تایم‌لاین؛ بقیه‌ی رویدادها از WebSocket فرستاده می‌شوند
+		for ev := range live {
+			if ev.Type != eventsPort.TypeTimelinePost || replayed[ev.ID] {
+				continue
+			}
+			if !send(ev) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// pageLimit محدود کردن limit درخواست به بازه‌ی ۱ تا maxPageLimit؛ مقدار صفر یا منفی یعنی مقدار پیش‌فرض
+func pageLimit(limit int64) int64 {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
 func (s *TimelineService) Add(ctx context.Context, tl *timeline.Timeline) error {
 	return s.TimelineRepository.Add(ctx, tl)
 }
+
+// WarmTimelines بازسازی timeline ZSET همه‌ی کاربرانی که از since به بعد فعال بوده‌اند (مثلاً بعد از ری‌استارت Redis)
+func (s *TimelineService) WarmTimelines(ctx context.Context, since time.Time, batchSize int) (users, entries int64, err error) {
+	after := ""
+	for {
+		userIDs, err := s.TimelineRepository.ActiveUserIDs(ctx, since, after, batchSize)
+		if err != nil {
+			return users, entries, err
+		}
+		if len(userIDs) == 0 {
+			return users, entries, nil
+		}
+
+		for _, userID := range userIDs {
+			count, err := s.TimelineRepository.Rebuild(ctx, userID)
+			if err != nil {
+				log.Println("❌ Error rebuilding timeline:", userID, err)
+				continue
+			}
+			users++
+			entries += count
+		}
+
+		log.Printf("🔥 Warmed %d timelines so far\n", users)
+		after = userIDs[len(userIDs)-1]
+	}
+}
+
+// RescoreLegacyTimelines بازسازی یک‌باره‌ی timeline ZSET هایی که هنوز امتیاز ثانیه‌ای (قبل از امتیاز میلی‌ثانیه‌ای) دارند
+func (s *TimelineService) RescoreLegacyTimelines(ctx context.Context, batchSize int) (users, entries int64, err error) {
+	var cursor uint64
+	for {
+		userIDs, next, err := s.TimelineRepository.LegacyTimelineUserIDs(ctx, cursor, int64(batchSize))
+		if err != nil {
+			return users, entries, err
+		}
+
+		for _, userID := range userIDs {
+			count, err := s.TimelineRepository.Rebuild(ctx, userID)
+			if err != nil {
+				log.Println("❌ Error rescoring timeline:", userID, err)
+				continue
+			}
+			users++
+			entries += count
+		}
+
+		if next == 0 {
+			return users, entries, nil
+		}
+		cursor = next
+	}
+}
@@ -9,8 +9,8 @@ import (
 
 type Timeline struct {
 	ID        uuid.UUID  `gorm:"primary_key;type:char(36);default:uuid()"`
-	UserID    uuid.UUID  `gorm:"type:char(36);not null"`
-	PostID    uuid.UUID  `gorm:"type:char(36);not null"`
+	UserID    uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_timelines_user_post,priority:1"`
+	PostID    uuid.UUID  `gorm:"type:char(36);not null;uniqueIndex:idx_timelines_user_post,priority:2"` // هر پست فقط یک بار در تایم‌لاین هر کاربر
 	CreatedAt time.Time  `gorm:"autoCreateTime"`
 	DeletedAt *time.Time `gorm:"index"`
 	
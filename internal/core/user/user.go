@@ -12,6 +12,7 @@ type User struct {
 	Username  string    `gorm:"unique;not null"`
 	Mobile    string    `gorm:"unique;not null"`
 	Password  string    `gorm:"not null"`
+	FollowerCount int64 `gorm:"not null;default:0;index"` // تعداد followers؛ همراه با follow/آنفالو به‌روز می‌شود تا celebrity ها بدون شمارش جدول followers پیدا شوند
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 	DeletedAt *time.Time `gorm:"index"`
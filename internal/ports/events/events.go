@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// انواع رویدادهایی که برای کاربر فرستاده می‌شوند
+const (
+	TypeTimelinePost = "timeline.post" // پست جدید در تایم‌لاین؛ Data یک PostDTO است
+	TypeFollow       = "follow"        // کاربر دیگری این کاربر را دنبال کرده؛ Data یک FollowEventDTO است
+)
+
+// Event رویدادی که برای یک کاربر منتشر می‌شود و هر نمونه‌ی API که اتصال کاربر را دارد آن را دریافت می‌کند.
+// برای رویدادهای تایم‌لاین ID همان cursor پست است و کلاینت بعد از قطع اتصال با آن ادامه می‌دهد
+type Event struct {
+	Type string          `json:"type"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data"`
+}
+
+// NewEvent ساخت رویداد با data سریالایزشده به JSON
+func NewEvent(eventType, id string, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Event{Type: eventType, ID: id, Data: raw}, nil
+}
+
+// FollowEventDTO اطلاعات رویداد follow
+type FollowEventDTO struct {
+	FollowerID string `json:"follower_id"`
+	FolloweeID string `json:"followee_id"`
+}
+
+// Publisher ارسال رویداد برای کاربران
+type Publisher interface {
+	Publish(ctx context.Context, userIDs []string, ev *Event) error
+}
+
+// Subscriber دریافت همه‌ی رویدادهای یک کاربر تا لغو ctx
+type Subscriber interface {
+	Subscribe(ctx context.Context, userID string) (<-chan *Event, error)
+}
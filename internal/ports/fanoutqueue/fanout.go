@@ -2,30 +2,94 @@ package fanout
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 	"virast/internal/core/fanoutqueue"
 
 	"github.com/gofrs/uuid"
 )
 
+// ErrLeaseLost رکورد دیگر در اختیار این worker نیست (lease منقضی و توسط worker دیگری گرفته شده)
+var ErrLeaseLost = errors.New("fanout lease lost")
+
 type FanoutRepository interface {
 	Create(ctx context.Context, fanout *fanoutqueue.FanoutQueue) (*fanoutqueue.FanoutQueue, error)
-	GetPendingPosts(ctx context.Context, limit int64) ([]*fanoutqueue.FanoutQueue, error)
-	MarkDone(ctx context.Context, id uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*fanoutqueue.FanoutQueue, error)
+	ClaimPending(ctx context.Context, workerID string, limit int, leaseTTL time.Duration) ([]*fanoutqueue.FanoutQueue, error)
+	ClaimByID(ctx context.Context, id uuid.UUID, workerID string, leaseTTL time.Duration) (*fanoutqueue.FanoutQueue, error)
+	SaveCheckpoint(ctx context.Context, id uuid.UUID, workerID, lastFollowerID string, leaseTTL time.Duration) error
+	ReclaimExpired(ctx context.Context, maxAttempts int) (int64, error) // lease منقضی‌شده یک تلاش حساب می‌شود
+	GetUnpublished(ctx context.Context, olderThan time.Time, limit int) ([]*fanoutqueue.FanoutQueue, error)
+	MarkPublished(ctx context.Context, ids ...uuid.UUID) error
+	// MarkDone، MarkFailed و Retry فقط رکورد processing در اختیار workerID را تغییر می‌دهند و در غیر این صورت ErrLeaseLost برمی‌گردانند
+	MarkDone(ctx context.Context, id uuid.UUID, workerID string) error
+	MarkFailed(ctx context.Context, id uuid.UUID, workerID, lastErr string) error
+	Retry(ctx context.Context, id uuid.UUID, workerID string, nextAttemptAt time.Time, lastErr string) error
+	Release(ctx context.Context, id uuid.UUID, workerID, lastErr string) error // برگرداندن به pending بدون شمردن تلاش
 }
 
 type FanoutRedis interface {
-	PushPostToFollowers(ctx context.Context, postID string, followerIDs []string) error
+	PushPostToFollowers(ctx context.Context, postID string, createdAt time.Time, followerIDs []string) error
+	AddPostsToTimeline(ctx context.Context, userID string, posts map[string]time.Time) error // postID -> زمان ساخت پست
+	RemovePostsFromTimeline(ctx context.Context, userID string, postIDs []string) error
+	RemovePostFromTimelines(ctx context.Context, postID string, userIDs []string) error
+	Available() bool            // false وقتی Redis قطع است و fanout ها باید در MySQL منتظر بمانند
+	Recovering() bool           // true وقتی Redis در حال آزمایش بعد از قطعی است و فقط یک رکورد باید برداشته شود
+	Unavailable(err error) bool // خطا ناشی از قطعی Redis است و تلاش ناموفق رکورد حساب نمی‌شود
+}
+
+// PushError خطای دستورات یک batch برای بعضی از کلیدهای timeline (مثلاً WRONGTYPE)؛ بقیه‌ی timeline ها
+// با موفقیت به‌روز شده‌اند و تکرار batch این کلیدها را درست نمی‌کند. قطعی Redis با این نوع گزارش نمی‌شود
+type PushError struct {
+	PostID     string
+	FailedKeys map[string]error // کلید timeline -> خطا
+}
+
+func (e *PushError) Error() string {
+	for key, err := range e.FailedKeys {
+		return fmt.Sprintf("push post %s failed for %d timelines (e.g. %s: %v)", e.PostID, len(e.FailedKeys), key, err)
+	}
+	return fmt.Sprintf("push post %s failed", e.PostID)
+}
+
+// FanoutQueue صف پیام‌های fanout بین PostService و FanoutWorker ها
+// (رکورد fanout_queue در MySQL همچنان سابقه‌ی پایدار است)
+type FanoutQueue interface {
+	Enqueue(ctx context.Context, msg *FanoutMessage) error
+	Consume(ctx context.Context, consumer string, count int64, block time.Duration) ([]*FanoutDelivery, error)
+	ClaimStale(ctx context.Context, consumer string, minIdle time.Duration, count int64) ([]*FanoutDelivery, error)
+	Ack(ctx context.Context, deliveryIDs ...string) error
 }
 
 // مدل پیام در صف
 type FanoutMessage struct {
+	QueueID  string
 	PostID   string
 	AuthorID string
 }
 
+// NewFanoutMessage ساخت پیام صف از روی رکورد outbox
+func NewFanoutMessage(fq *fanoutqueue.FanoutQueue) *FanoutMessage {
+	msg := &FanoutMessage{
+		QueueID:  fq.ID.String(),
+		AuthorID: fq.UserID.String(),
+	}
+	if fq.PostID != nil {
+		msg.PostID = fq.PostID.String()
+	}
+	return msg
+}
+
+// FanoutDelivery پیام تحویل‌شده به worker همراه با شناسه‌ی لازم برای Ack
+type FanoutDelivery struct {
+	ID      string
+	Message FanoutMessage
+}
+
 type FanoutQueueDTO struct {
 	ID     uuid.UUID
 	PostID uuid.UUID
 	UserID uuid.UUID
-	Status string // pending, done, failed
+	Status string // pending, processing, done, failed
 }
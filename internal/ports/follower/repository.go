@@ -2,16 +2,27 @@ package follower
 
 import (
 	"context"
+	"errors"
 	"virast/internal/core/follower"
 )
 
+var (
+	// ErrAlreadyFollowing ردیف follow قبلاً ثبت شده است (follow تکراری یا درخواست دوباره ارسال‌شده)
+	ErrAlreadyFollowing = errors.New("already following")
+	// ErrNotFollowing ردیف follow برای حذف وجود ندارد (آنفالو تکراری یا کاربری که دنبال نشده)
+	ErrNotFollowing = errors.New("not following")
+)
+
 // FollowerRepository پورت برای ذخیره‌سازی و بازیابی دنبال‌کنندگان
 type FollowerRepository interface {
-	FollowUser(ctx context.Context, follower *follower.Follower) (*follower.Follower, error)
-	UnfollowUser(ctx context.Context, followerID, followeeID string) error
+	FollowUser(ctx context.Context, follower *follower.Follower) (*follower.Follower, error) // اگر follow وجود داشته باشد ErrAlreadyFollowing
+	UnfollowUser(ctx context.Context, followerID, followeeID string) error                   // اگر ردیفی حذف نشود ErrNotFollowing
 	GetFollowersByUserID(ctx context.Context, userID string) ([]*follower.Follower, error)
+	GetFollowersPage(ctx context.Context, userID, afterFollowerID string, limit int) ([]*follower.Follower, error)
+	CountFollowers(ctx context.Context, userID string) (int64, error)
 	GetFollowingByUserID(ctx context.Context, followerID string) ([]*follower.Follower, error)
 	IsFollowing(ctx context.Context, followerID, followeeID string) (bool, error)
+	FilterFollowing(ctx context.Context, followerIDs []string, followeeID string) ([]string, error) // آن‌هایی از followerIDs که followeeID را دنبال می‌کنند
 }
 
 // DTOها برای UseCase
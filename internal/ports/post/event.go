@@ -0,0 +1,21 @@
+package post
+
+import (
+	"virast/internal/core/post"
+	"virast/internal/core/timeline"
+	eventsPort "virast/internal/ports/events"
+)
+
+// NewTimelineEvent رویداد timeline.post برای پست p با cursor آن به عنوان شناسه‌ی رویداد؛
+// اگر lookup نباشد (مثلاً خواندن نویسنده ناموفق بوده) DTO بدون نویسنده ساخته می‌شود
+func NewTimelineEvent(lookup *PostLookup, p *post.Post) (*eventsPort.Event, error) {
+	cursor := timeline.Cursor{Score: timeline.Score(p.CreatedAt), PostID: p.ID.String()}.Encode()
+	var dto *PostDTO
+	if lookup != nil {
+		dto = lookup.DTO(p)
+	} else {
+		dto = NewPostDTO(p)
+	}
+	dto.Cursor = cursor
+	return eventsPort.NewEvent(eventsPort.TypeTimelinePost, cursor, dto)
+}
@@ -0,0 +1,100 @@
+package post
+
+import (
+	"virast/internal/core/post"
+	userPort "virast/internal/ports/user"
+)
+
+// embedDepth تعداد سطح‌های repost_of و quoted_post که در DTO قرار می‌گیرند
+// (برای repost یک نقل‌قول: خود repost، نقل‌قول و پست نقل‌قول‌شده)
+const embedDepth = 2
+
+// PostLookup پست‌های ارجاع‌شده و نویسندگان مورد نیاز برای ساخت PostDTO کامل
+type PostLookup struct {
+	Posts   map[string]*post.Post
+	Authors map[string]*userPort.UserDTO // فقط کاربران حذف‌نشده
+}
+
+// LoadPostLookup خواندن پست‌هایی که posts به آن‌ها ارجاع می‌دهند (تا embedDepth سطح) و نویسندگان همه‌ی آن‌ها؛
+// برای هر سطح یک FindByIDs و برای نویسندگان یک FindByIDs
+func LoadPostLookup(posts PostRepository, users userPort.UserRepository, roots []*post.Post) (*PostLookup, error) {
+	l := &PostLookup{
+		Posts:   make(map[string]*post.Post, len(roots)),
+		Authors: make(map[string]*userPort.UserDTO),
+	}
+	for _, p := range roots {
+		l.Posts[p.ID.String()] = p
+	}
+
+	level := roots
+	for depth := 0; depth < embedDepth && len(level) > 0; depth++ {
+		var ids []string
+		for _, p := range level {
+			if ref := p.ReferencedID(); ref != nil {
+				if _, ok := l.Posts[ref.String()]; !ok {
+					ids = append(ids, ref.String())
+				}
+			}
+		}
+		if len(ids) == 0 {
+			break
+		}
+		found, err := posts.FindByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range found {
+			l.Posts[p.ID.String()] = p
+		}
+		level = found
+	}
+
+	authorIDs := make([]string, 0, len(l.Posts))
+	seen := make(map[string]bool, len(l.Posts))
+	for _, p := range l.Posts {
+		if id := p.UserID.String(); !seen[id] {
+			seen[id] = true
+			authorIDs = append(authorIDs, id)
+		}
+	}
+	authors, err := users.FindByIDs(authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range authors {
+		if u.DeletedAt != nil {
+			continue
+		}
+		l.Authors[u.ID.String()] = &userPort.UserDTO{
+			ID:       u.ID.String(),
+			Username: u.Username,
+			Mobile:   u.Mobile,
+		}
+	}
+	return l, nil
+}
+
+// DTO تبدیل پست به DTO با نویسنده و پست repost یا نقل‌قول‌شده؛
+// اگر پست ارجاع‌شده حذف شده باشد فیلد آن خالی می‌ماند
+func (l *PostLookup) DTO(p *post.Post) *PostDTO {
+	return l.dto(p, embedDepth)
+}
+
+func (l *PostLookup) dto(p *post.Post, depth int) *PostDTO {
+	dto := NewPostDTO(p)
+	dto.User = l.Authors[p.UserID.String()]
+	if depth == 0 {
+		return dto
+	}
+	if p.RepostOfID != nil {
+		if orig, ok := l.Posts[p.RepostOfID.String()]; ok {
+			dto.RepostOf = l.dto(orig, depth-1)
+		}
+	}
+	if p.QuotedPostID != nil {
+		if quoted, ok := l.Posts[p.QuotedPostID.String()]; ok {
+			dto.QuotedPost = l.dto(quoted, depth-1)
+		}
+	}
+	return dto
+}
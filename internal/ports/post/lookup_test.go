@@ -0,0 +1,134 @@
+package post
+
+import (
+	"testing"
+	"time"
+	"virast/internal/core/post"
+	"virast/internal/core/user"
+	userPort "virast/internal/ports/user"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakePostRepository پست‌ها را برعکس ترتیب شناسه‌های درخواستی برمی‌گرداند، مثل IN (...) در MySQL که ترتیبی تضمین نمی‌کند
+type fakePostRepository struct {
+	PostRepository
+	posts map[string]*post.Post
+	calls int
+}
+
+func (r *fakePostRepository) FindByIDs(ids []string) ([]*post.Post, error) {
+	r.calls++
+	var found []*post.Post
+	for i := len(ids) - 1; i >= 0; i-- {
+		if p, ok := r.posts[ids[i]]; ok {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+type fakeUserRepository struct {
+	userPort.UserRepository
+	users map[string]*user.User
+	calls int
+}
+
+func (r *fakeUserRepository) FindByIDs(ids []string) ([]*user.User, error) {
+	r.calls++
+	var found []*user.User
+	for i := len(ids) - 1; i >= 0; i-- {
+		if u, ok := r.users[ids[i]]; ok {
+			found = append(found, u)
+		}
+	}
+	return found, nil
+}
+
+func TestLoadPostLookup(t *testing.T) {
+	newID := func() uuid.UUID { return uuid.Must(uuid.NewV4()) }
+	deletedAt := time.Now()
+
+	alice := &user.User{ID: newID(), Username: "alice"}
+	bob := &user.User{ID: newID(), Username: "bob"}
+	gone := &user.User{ID: newID(), Username: "gone", DeletedAt: &deletedAt}
+
+	orig := &post.Post{ID: newID(), UserID: alice.ID, Content: "original"}
+	quote := &post.Post{ID: newID(), UserID: bob.ID, Content: "quote", QuotedPostID: &orig.ID}
+	repost := &post.Post{ID: newID(), UserID: alice.ID, RepostOfID: &quote.ID}
+	repostOfRepostChain := &post.Post{ID: newID(), UserID: bob.ID, RepostOfID: &repost.ID}
+	byDeleted := &post.Post{ID: newID(), UserID: gone.ID, Content: "deleted author"}
+	missingID := newID()
+	dangling := &post.Post{ID: newID(), UserID: bob.ID, RepostOfID: &missingID}
+
+	posts := &fakePostRepository{posts: map[string]*post.Post{}}
+	for _, p := range []*post.Post{orig, quote, repost, repostOfRepostChain, byDeleted, dangling} {
+		posts.posts[p.ID.String()] = p
+	}
+	users := &fakeUserRepository{users: map[string]*user.User{
+		alice.ID.String(): alice,
+		bob.ID.String():   bob,
+		gone.ID.String():  gone,
+	}}
+
+	tests := []struct {
+		name       string
+		root       *post.Post
+		wantUser   string // نام کاربری نویسنده؛ خالی یعنی User باید nil باشد
+		wantRepost string // شناسه‌ی RepostOf
+		wantQuoted string // شناسه‌ی RepostOf.QuotedPost یا QuotedPost
+	}{
+		{"plain post", orig, "alice", "", ""},
+		{"quote embeds quoted post", quote, "bob", "", orig.ID.String()},
+		{"repost of quote embeds both levels", repost, "alice", quote.ID.String(), orig.ID.String()},
+		{"embedding stops at embedDepth", repostOfRepostChain, "bob", repost.ID.String(), ""},
+		{"deleted author hidden", byDeleted, "", "", ""},
+		{"missing original left empty", dangling, "bob", "", ""},
+	}
+
+	roots := make([]*post.Post, 0, len(tests))
+	for _, tt := range tests {
+		roots = append(roots, tt.root)
+	}
+	lookup, err := LoadPostLookup(posts, users, roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts.calls > embedDepth || users.calls != 1 {
+		t.Errorf("FindByIDs calls: posts=%d users=%d, want posts<=%d users=1", posts.calls, users.calls, embedDepth)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto := lookup.DTO(tt.root)
+			if dto.ID != tt.root.ID.String() {
+				t.Fatalf("ID = %s, want %s", dto.ID, tt.root.ID)
+			}
+
+			gotUser := ""
+			if dto.User != nil {
+				gotUser = dto.User.Username
+			}
+			if gotUser != tt.wantUser {
+				t.Errorf("User = %q, want %q", gotUser, tt.wantUser)
+			}
+
+			gotRepost, gotQuoted := "", ""
+			if dto.RepostOf != nil {
+				gotRepost = dto.RepostOf.ID
+				if dto.RepostOf.QuotedPost != nil {
+					gotQuoted = dto.RepostOf.QuotedPost.ID
+				}
+				if dto.RepostOf.RepostOf != nil && dto.RepostOf.RepostOf.RepostOf != nil {
+					t.Errorf("embedded deeper than embedDepth")
+				}
+			}
+			if dto.QuotedPost != nil {
+				gotQuoted = dto.QuotedPost.ID
+			}
+			if gotRepost != tt.wantRepost || gotQuoted != tt.wantQuoted {
+				t.Errorf("RepostOf, QuotedPost = (%q, %q), want (%q, %q)", gotRepost, gotQuoted, tt.wantRepost, tt.wantQuoted)
+			}
+		})
+	}
+}
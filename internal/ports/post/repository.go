@@ -1,22 +1,109 @@
 package post
 
 import (
+	"context"
+	"errors"
 	"virast/internal/core/post"
+	"virast/internal/core/timeline"
 	userPort "virast/internal/ports/user"
 )
 
+var (
+	// ErrPostNotFound پست وجود ندارد یا حذف شده است
+	ErrPostNotFound = errors.New("post not found")
+	// ErrNotPostAuthor فقط نویسنده‌ی پست اجازه‌ی این کار را دارد
+	ErrNotPostAuthor = errors.New("only the author can modify this post")
+	// ErrEditWindowClosed مهلت ویرایش پست (POST_EDIT_WINDOW) تمام شده است
+	ErrEditWindowClosed = errors.New("post edit window has expired")
+	// ErrRepostNotEditable repost محتوای خودش را ندارد و ویرایش نمی‌شود
+	ErrRepostNotEditable = errors.New("reposts cannot be edited")
+	// ErrAlreadyReposted کاربر قبلاً این پست را repost کرده است
+	ErrAlreadyReposted = errors.New("post already reposted")
+)
+
 // PostRepository پورت برای ذخیره‌سازی و بازیابی پست‌ها
 type PostRepository interface {
 	Create(post *post.Post) (*post.Post, error)
 	FindByID(id string) (*post.Post, error)
-	FindByUserID(userID string) ([]*post.Post, error)
+	FindByIDForUpdate(id string) (*post.Post, error)                                         // مثل FindByID با قفل ردیف تا پایان تراکنش UnitOfWork
+	FindByIDs(ids []string) ([]*post.Post, error)                                            // پست‌های حذف‌شده برگردانده نمی‌شوند
+	FindByUserID(userID string, limit int) ([]*post.Post, error)                             // جدیدترین پست‌ها؛ limit صفر یعنی همه
+	FindPageByUserID(userID string, after *timeline.Cursor, limit int) ([]*post.Post, error) // صفحه‌ی پروفایل با keyset cursor
+	Delete(id string) error                                                                  // حذف نرم (deleted_at)
+	Update(post *post.Post) error                                                            // ذخیره‌ی Content و EditedAt پست حذف‌نشده
+	AddRevision(rev *post.PostRevision) error
+	FindRevisions(postID string) ([]*post.PostRevision, error)                            // از جدید به قدیم
+	FindReplies(parentID string, after *timeline.Cursor, limit int) ([]*post.Post, error) // پاسخ‌های مستقیم از قدیم به جدید
+	AddReplyCount(id string, delta int) error
+	FindRepost(userID, postID string) (*post.Post, error) // repost حذف‌نشده‌ی کاربر از postID؛ در غیر این صورت ErrPostNotFound
+}
+
+// PostCache پورت برای باطل کردن cache پست‌ها بعد از ویرایش یا حذف
+type PostCache interface {
+	Invalidate(ctx context.Context, ids ...string) error
 }
 
 // DTOها برای UseCase
 type PostDTO struct {
-	ID        string            `json:"id"`
-	Content   string            `json:"content"`
-	UserID    string            `json:"user_id"`
-	User      *userPort.UserDTO `json:"user,omitempty"`
-	CreatedAt string            `json:"created_at"`
+	ID         string            `json:"id"`
+	Content    string            `json:"content"`
+	UserID     string            `json:"user_id"`
+	User       *userPort.UserDTO `json:"user,omitempty"`
+	CreatedAt  string            `json:"created_at"`
+	EditedAt   string            `json:"edited_at,omitempty"` // فقط برای پست‌های ویرایش‌شده
+	ReplyToID  string            `json:"reply_to_id,omitempty"`
+	RootID     string            `json:"root_id,omitempty"`
+	ReplyCount int64             `json:"reply_count"`
+	RepostOf   *PostDTO          `json:"repost_of,omitempty"`   // برای repost: پست اصلی؛ User همان کسی است که repost کرده
+	QuotedPost *PostDTO          `json:"quoted_post,omitempty"` // برای نقل‌قول: پست نقل‌قول‌شده (اگر حذف نشده باشد)
+	Cursor     string            `json:"cursor,omitempty"`      // موقعیت پست در لیست؛ فقط در پاسخ‌های صفحه‌بندی‌شده
+}
+
+// NewPostDTO تبدیل پست به DTO بدون نویسنده و cursor
+func NewPostDTO(p *post.Post) *PostDTO {
+	dto := &PostDTO{
+		ID:         p.ID.String(),
+		Content:    p.Content,
+		UserID:     p.UserID.String(),
+		CreatedAt:  p.CreatedAt.String(),
+		ReplyCount: p.ReplyCount,
+	}
+	if p.EditedAt != nil {
+		dto.EditedAt = p.EditedAt.String()
+	}
+	if p.ReplyToID != nil {
+		dto.ReplyToID = p.ReplyToID.String()
+	}
+	if p.RootID != nil {
+		dto.RootID = p.RootID.String()
+	}
+	return dto
+}
+
+// UserPostsPage یک صفحه از پست‌های یک کاربر (صفحه‌ی پروفایل)
+type UserPostsPage struct {
+	User       *userPort.UserDTO `json:"user"`
+	Posts      []*PostDTO        `json:"posts"`
+	NextCursor string            `json:"next_cursor"` // خالی یعنی صفحه‌ی دیگری وجود ندارد
+}
+
+// ThreadDTO یک پست و پاسخ‌های مستقیم آن؛ هر سطح جداگانه با next_cursor صفحه‌بندی می‌شود
+// (صفحه‌ی بعدِ پاسخ‌های هر پست با GET /posts/:id/thread?cursor=... همان پست خوانده می‌شود)
+type ThreadDTO struct {
+	Post       *PostDTO     `json:"post"`
+	Replies    []*ThreadDTO `json:"replies"`
+	NextCursor string       `json:"next_cursor,omitempty"` // خالی یعنی پاسخ دیگری در این سطح نیست
+}
+
+// PostRevisionDTO یک نسخه‌ی قبلی پست
+type PostRevisionDTO struct {
+	Content     string `json:"content"`
+	PublishedAt string `json:"published_at"`
+	ReplacedAt  string `json:"replaced_at"`
+}
+
+// PostHistoryDTO محتوای فعلی پست به همراه نسخه‌های قبلی آن (از جدید به قدیم)
+type PostHistoryDTO struct {
+	Post      *PostDTO           `json:"post"`
+	Revisions []*PostRevisionDTO `json:"revisions"`
 }
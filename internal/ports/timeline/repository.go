@@ -2,11 +2,42 @@ package timeline
 
 import (
 	"context"
+	"errors"
+	"time"
 	"virast/internal/core/timeline"
 	postPort "virast/internal/ports/post"
 )
+
+// ErrInvalidCursor cursor ارسال‌شده توسط کلاینت قابل خواندن نیست
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type TimelineRepository interface {
 	GetTimelineByUserID(ctx context.Context, userID string, start, limit int64) ([]*postPort.PostDTO, error)
+	GetTimelinePage(ctx context.Context, userID string, cursor *timeline.Cursor, limit int64) (*TimelinePage, error)
+	CountNewer(ctx context.Context, userID string, since *timeline.Cursor) (int64, error)
+	GetNewerThan(ctx context.Context, userID string, since *timeline.Cursor, limit int64) ([]*postPort.PostDTO, error)
 	Add(ctx context.Context, tl *timeline.Timeline) error
 	AddBatch(ctx context.Context, timelines []*timeline.Timeline) error
+	Rebuild(ctx context.Context, userID string) (int64, error)
+	LegacyTimelineUserIDs(ctx context.Context, cursor uint64, count int64) ([]string, uint64, error) // کاربرانی که ZSET آن‌ها امتیاز ثانیه‌ای قدیمی دارد
+	ActiveUserIDs(ctx context.Context, since time.Time, afterUserID string, limit int) ([]string, error)
+	FindAuthorPostIDs(ctx context.Context, userID, authorID string, limit int) ([]string, error)
+	RemovePosts(ctx context.Context, userID string, postIDs []string) error
+	FindPostUserIDs(ctx context.Context, postID string, limit int) ([]string, error)
+	FindUsersWithPost(ctx context.Context, postID string, userIDs []string) ([]string, error) // آن‌هایی از userIDs که پست یا repost آن را در تایم‌لاین دارند
+	RemovePostForUsers(ctx context.Context, postID string, userIDs []string) error
+}
+
+// TimelinePage یک صفحه از تایم‌لاین؛ NextCursor خالی یعنی صفحه‌ی بعدی وجود ندارد.
+// LatestCursor فقط در صفحه‌ی اول پر می‌شود و برای پرسیدن پست‌های جدید (since) استفاده می‌شود
+type TimelinePage struct {
+	Posts        []*postPort.PostDTO `json:"timeline"`
+	NextCursor   string              `json:"next_cursor"`
+	LatestCursor string              `json:"latest_cursor,omitempty"`
+}
+
+// NewPostsDTO تعداد (و در صورت درخواست، خود) پست‌های جدیدتر از cursor کلاینت
+type NewPostsDTO struct {
+	Count int64               `json:"count"`
+	Posts []*postPort.PostDTO `json:"posts,omitempty"`
 }
@@ -0,0 +1,22 @@
+package uow
+
+import (
+	"context"
+	fanoutPort "virast/internal/ports/fanoutqueue"
+	followerPort "virast/internal/ports/follower"
+	postPort "virast/internal/ports/post"
+	timelinePort "virast/internal/ports/timeline"
+)
+
+// Repositories مخزن‌هایی که داخل یک تراکنش در دسترس هستند
+type Repositories struct {
+	Posts     postPort.PostRepository
+	Fanouts   fanoutPort.FanoutRepository
+	Timelines timelinePort.TimelineRepository
+	Followers followerPort.FollowerRepository
+}
+
+// UnitOfWork پورت اجرای چند عملیات ذخیره‌سازی در یک تراکنش؛ اگر fn خطا برگرداند همه‌ی تغییرات برگردانده می‌شوند
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(repos Repositories) error) error
+}
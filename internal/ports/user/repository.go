@@ -1,12 +1,19 @@
 package user
 
-import "virast/internal/core/user"
+import (
+	"errors"
+	"virast/internal/core/user"
+)
+
+// ErrUserNotFound کاربر وجود ندارد یا حذف شده است
+var ErrUserNotFound = errors.New("user not found")
 
 // UserRepository پورت برای ذخیره‌سازی و بازیابی کاربران
 type UserRepository interface {
 	Create(user *user.User) (*user.User, error)
 	FindByUsernameOrMobile(username, mobile string) (*user.User, error)
 	FindByUsername(username string) (*user.User, error)
+	FindByIDs(ids []string) ([]*user.User, error)
 }
 
 // DTOها برای UseCase
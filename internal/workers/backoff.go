@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff فاصله‌ی تلاش بعدی: base * 2^(attempt-1) با سقف maxDelay و jitter در نیمه‌ی دوم بازه
+// تا workerها بعد از یک خطای مشترک (مثلاً قطعی Redis) همزمان دوباره تلاش نکنند
+func backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := maxDelay
+	if shift := attempt - 1; shift < 32 {
+		if d := base << shift; d > 0 && d < maxDelay {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,34 @@
+package workers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	const base = time.Second
+	const maxDelay = time.Minute
+
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration // سقف بازه؛ مقدار برگشتی در [want/2, want] است
+	}{
+		{"zero attempt treated as first", 0, time.Second},
+		{"first attempt", 1, time.Second},
+		{"second attempt doubles", 2, 2 * time.Second},
+		{"fifth attempt", 5, 16 * time.Second},
+		{"capped at max", 7, maxDelay},
+		{"huge attempt does not overflow", 1000, maxDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := backoff(tt.attempt, base, maxDelay)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("backoff(%d) = %s, want in [%s, %s]", tt.attempt, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -2,142 +2,774 @@ package workers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"virast/internal/core/fanoutqueue"
-	//"virast/internal/core/post"
+	"virast/internal/core/post"
 	timelineEntity "virast/internal/core/timeline"
 	//"virast/internal/core/user"
+	eventsPort "virast/internal/ports/events"
 	fanoutPort "virast/internal/ports/fanoutqueue"
 	followerPort "virast/internal/ports/follower"
+	postPort "virast/internal/ports/post"
 	timelinePort "virast/internal/ports/timeline"
+	userPort "virast/internal/ports/user"
 
 	"github.com/gofrs/uuid"
 )
 
-type FanoutWorker struct {
+const (
+	streamReadCount = 10               // تعداد پیام‌های خوانده‌شده در هر XREADGROUP
+	streamBlock     = 5 * time.Second  // مدت انتظار XREADGROUP برای پیام جدید
+	sweepInterval   = 10 * time.Second // فاصله‌ی بررسی رکوردهای pending و lease های منقضی در MySQL
+	retryMaxDelay   = 10 * time.Minute // سقف backoff بین دو تلاش
+)
+
+// مخاطبان fanout پاسخ‌ها (REPLY_FANOUT)
+const (
+	ReplyFanoutMutual = "mutual" // فقط followers نویسنده‌ی پاسخ که نویسنده‌ی پست والد را هم دنبال می‌کنند
+	ReplyFanoutAll    = "all"    // همه‌ی followers نویسنده‌ی پاسخ، مثل پست معمولی
+	ReplyFanoutNone   = "none"   // پاسخ فقط در thread و تایم‌لاین خود نویسنده دیده می‌شود
+)
+
+// errInvalidFanout رکوردی که هیچ‌وقت قابل پردازش نیست و بدون تلاش مجدد failed می‌شود
+var errInvalidFanout = errors.New("invalid fanout_queue record")
+
+// FanoutWorkerConfig وابستگی‌ها و تنظیمات FanoutWorker؛ فیلدها با نام مقداردهی می‌شوند
+// تا وابستگی جاافتاده یا جابه‌جا شده در NewFanoutWorker پیدا شود
+type FanoutWorkerConfig struct {
 	FanoutRepo   fanoutPort.FanoutRepository
+	Queue        fanoutPort.FanoutQueue
 	FanoutRedis  fanoutPort.FanoutRedis
+	PostRepo     postPort.PostRepository
 	FollowerRepo followerPort.FollowerRepository
 	TimelineRepo timelinePort.TimelineRepository
-	BatchSize    int // تعداد رکوردهای batch برای Redis و timeline
+	UserRepo     userPort.UserRepository
+	Events       eventsPort.Publisher // اطلاع‌رسانی زنده به followers بعد از هر batch
+	BatchSize    int                  // تعداد رکوردهای batch برای Redis و timeline
+	WorkerID     string               // نام consumer در consumer group
+	ClaimIdle    time.Duration        // پیام‌هایی که بیشتر از این مدت ack نشده‌اند دوباره برداشته می‌شوند
+	LeaseTTL     time.Duration        // مدت اعتبار lease روی رکورد fanout_queue
+	MaxAttempts  int                  // بعد از این تعداد تلاش ناموفق رکورد failed می‌شود
+	RetryBase    time.Duration        // فاصله‌ی پایه‌ی backoff
+	// CelebrityThreshold نویسندگانی با followers بیشتر از این مقدار push نمی‌شوند (pull موقع خواندن تایم‌لاین)
+	CelebrityThreshold int64
+	// BackfillLimit تعداد آخرین پست‌های followee که بعد از follow به تایم‌لاین اضافه می‌شوند
+	BackfillLimit int
+	// ReplyFanout مخاطبان پاسخ‌ها: ReplyFanoutMutual، ReplyFanoutAll یا ReplyFanoutNone
+	ReplyFanout string
+}
+
+type FanoutWorker struct {
+	FanoutWorkerConfig
 }
 
-func NewFanoutWorker(
-	fanoutRepo fanoutPort.FanoutRepository,
-	fanoutRedis fanoutPort.FanoutRedis,
-	followerRepo followerPort.FollowerRepository,
-	timelineRepo timelinePort.TimelineRepository,
-	batchSize int,
-) *FanoutWorker {
-	return &FanoutWorker{
-		FanoutRepo:   fanoutRepo,
-		FanoutRedis:  fanoutRedis,
-		FollowerRepo: followerRepo,
-		TimelineRepo: timelineRepo,
-		BatchSize:    batchSize,
+// NewFanoutWorker ساخت worker؛ اگر وابستگی‌ای مقداردهی نشده باشد خطا برمی‌گرداند
+func NewFanoutWorker(cfg FanoutWorkerConfig) (*FanoutWorker, error) {
+	deps := []struct {
+		name string
+		nil  bool
+	}{
+		{"FanoutRepo", cfg.FanoutRepo == nil},
+		{"Queue", cfg.Queue == nil},
+		{"FanoutRedis", cfg.FanoutRedis == nil},
+		{"PostRepo", cfg.PostRepo == nil},
+		{"FollowerRepo", cfg.FollowerRepo == nil},
+		{"TimelineRepo", cfg.TimelineRepo == nil},
+		{"UserRepo", cfg.UserRepo == nil},
+		{"Events", cfg.Events == nil},
+	}
+	for _, d := range deps {
+		if d.nil {
+			return nil, fmt.Errorf("fanout worker: %s is required", d.name)
+		}
+	}
+	if cfg.BatchSize <= 0 || cfg.MaxAttempts <= 0 || cfg.LeaseTTL <= 0 {
+		return nil, errors.New("fanout worker: BatchSize, MaxAttempts and LeaseTTL must be positive")
 	}
+	return &FanoutWorker{FanoutWorkerConfig: cfg}, nil
 }
 
 // Run گوش دادن به صف و توزیع پست‌ها
 func (w *FanoutWorker) Run(ctx context.Context) {
-	log.Println("🚀 FanoutWorker started")
+	log.Println("🚀 FanoutWorker started:", w.WorkerID)
+	lastClaim := time.Time{}
+	lastSweep := time.Time{}
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("🛑 Fanout worker stopped")
 			return
 		default:
-			// گرفتن رکوردهای pending
-			pendingPosts, err := w.FanoutRepo.GetPendingPosts(ctx, int64(w.BatchSize))
+		}
+
+		// برداشتن پیام‌هایی که worker دیگری گرفته ولی ack نکرده (مثلاً کرش کرده)
+		if time.Since(lastClaim) >= w.ClaimIdle {
+			stale, err := w.Queue.ClaimStale(ctx, w.WorkerID, w.ClaimIdle, streamReadCount)
 			if err != nil {
-				log.Println("❌ Error fetching pending posts:", err)
-				time.Sleep(time.Second)
-				continue
+				log.Println("❌ Error claiming stale fanout messages:", err)
+			} else if len(stale) > 0 {
+				log.Printf("♻️ Claimed %d stale fanout messages\n", len(stale))
+				w.handleDeliveries(ctx, stale)
 			}
+			lastClaim = time.Now()
+		}
 
-			//log.Printf("🔔 Found %d pending posts\n", len(pendingPosts))
+		// رکوردهایی که هیچ‌وقت به stream نرسیده‌اند یا lease آن‌ها منقضی شده
+		if time.Since(lastSweep) >= sweepInterval {
+			w.sweep(ctx)
+			lastSweep = time.Now()
+		}
 
-			for _, fq := range pendingPosts {
-				w.processFanout(ctx, fq)
+		deliveries, err := w.Queue.Consume(ctx, w.WorkerID, streamReadCount, streamBlock)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
 			}
+			log.Println("❌ Error reading fanout stream:", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		w.handleDeliveries(ctx, deliveries)
+	}
+}
+
+// handleDeliveries پردازش پیام‌های stream؛ رکورد ابتدا در MySQL claim می‌شود تا دو worker
+// یک رکورد را همزمان پردازش نکنند. اگر پردازش نیمه‌کاره بماند، lease منقضی می‌شود و sweep آن را برمی‌دارد
+func (w *FanoutWorker) handleDeliveries(ctx context.Context, deliveries []*fanoutPort.FanoutDelivery) {
+	for _, d := range deliveries {
+		id, err := uuid.FromString(d.Message.QueueID)
+		if err != nil {
+			log.Println("❌ Invalid fanout message, dropping:", d.ID, err)
+			w.ack(ctx, d.ID)
+			continue
+		}
+
+		fq, err := w.FanoutRepo.ClaimByID(ctx, id, w.WorkerID, w.LeaseTTL)
+		if err != nil {
+			log.Println("❌ Error claiming fanout_queue record:", id, err)
+			continue
+		}
 
-			time.Sleep(1000 * time.Millisecond)
+		// رکورد قبلاً انجام شده یا در اختیار worker دیگری است
+		if fq != nil {
+			w.handleFanout(ctx, fq)
 		}
+
+		w.ack(ctx, d.ID)
 	}
 }
 
-// پردازش یک رکورد FanoutQueue
-func (w *FanoutWorker) processFanout(ctx context.Context, fq *fanoutqueue.FanoutQueue) {
-	if fq == nil || fq.PostID == uuid.Nil || fq.UserID == uuid.Nil {
-		log.Println("❌ Invalid FanoutQueue record:", fq)
+// sweep آزاد کردن lease های منقضی و پردازش رکوردهای pending مستقیماً از MySQL؛
+// تا وقتی Redis در دسترس نیست رکوردی برداشته نمی‌شود و fanout ها در MySQL صف می‌مانند
+func (w *FanoutWorker) sweep(ctx context.Context) {
+	reclaimed, err := w.FanoutRepo.ReclaimExpired(ctx, w.MaxAttempts)
+	if err != nil {
+		log.Println("❌ Error reclaiming expired leases:", err)
+	} else if reclaimed > 0 {
+		log.Printf("♻️ Reclaimed %d fanout_queue records with expired lease\n", reclaimed)
+	}
+
+	if !w.FanoutRedis.Available() {
+		log.Println("⏸ Redis unavailable, leaving pending fanouts queued in MySQL")
 		return
 	}
 
-	log.Printf("➡ Processing FanoutQueue: PostID=%s AuthorID=%s\n", fq.PostID, fq.UserID)
+	// در حالت half-open فقط یک رکورد برداشته می‌شود تا بقیه با ErrCircuitOpen برنگردند
+	limit := streamReadCount
+	if w.FanoutRedis.Recovering() {
+		limit = 1
+	}
 
-	// گرفتن followers
-	followers, err := w.FollowerRepo.GetFollowersByUserID(ctx, fq.UserID.String())
+	claimed, err := w.FanoutRepo.ClaimPending(ctx, w.WorkerID, limit, w.LeaseTTL)
 	if err != nil {
-		log.Println("❌ Error fetching followers:", err)
+		log.Println("❌ Error claiming pending fanout_queue records:", err)
 		return
 	}
 
-	log.Printf("👥 Found %d followers for user %s\n", len(followers), fq.UserID)
+	for _, fq := range claimed {
+		w.handleFanout(ctx, fq)
+	}
+}
 
-	if len(followers) == 0 {
-		log.Println("⚠️ No followers for user:", fq.UserID)
-		if err := w.FanoutRepo.MarkDone(ctx, fq.ID); err != nil {
-			log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+// handleFanout پردازش رکورد و در صورت خطا زمان‌بندی تلاش مجدد با backoff یا انتقال به failed
+func (w *FanoutWorker) handleFanout(ctx context.Context, fq *fanoutqueue.FanoutQueue) {
+	err := w.processFanout(ctx, fq)
+	if err == nil || errors.Is(err, fanoutPort.ErrLeaseLost) {
+		// در حالت lease از دست رفته رکورد در اختیار worker دیگری است
+		return
+	}
+
+	// قطعی Redis تقصیر رکورد نیست؛ lease بدون شمردن تلاش آزاد می‌شود و sweep بعد از بازگشت Redis آن را برمی‌دارد
+	if w.FanoutRedis.Unavailable(err) {
+		log.Printf("⏸ FanoutQueue %s released, Redis unavailable: %v\n", fq.ID, err)
+		if err := w.FanoutRepo.Release(ctx, fq.ID, w.WorkerID, err.Error()); err != nil {
+			log.Println("⚠️ Warning: could not release fanout_queue lease:", err)
 		}
 		return
 	}
 
-	// تبدیل followers به []string
-	var followerIDs []string
-	for _, f := range followers {
-		followerIDs = append(followerIDs, f.FollowerID.String())
+	attempts := fq.Attempts + 1
+	if errors.Is(err, errInvalidFanout) || attempts >= w.MaxAttempts {
+		log.Printf("💀 FanoutQueue %s failed after %d attempts: %v\n", fq.ID, attempts, err)
+		if err := w.FanoutRepo.MarkFailed(ctx, fq.ID, w.WorkerID, err.Error()); err != nil {
+			log.Println("⚠️ Warning: could not mark fanout_queue failed:", err)
+		}
+		return
 	}
 
-	// پردازش batch برای Redis ZSET
-	for i := 0; i < len(followerIDs); i += w.BatchSize {
-		end := min(i+w.BatchSize, len(followerIDs))
-		batch := followerIDs[i:end]
+	delay := backoff(attempts, w.RetryBase, retryMaxDelay)
+	log.Printf("🔁 FanoutQueue %s attempt %d failed, retrying in %s: %v\n", fq.ID, attempts, delay, err)
+	if err := w.FanoutRepo.Retry(ctx, fq.ID, w.WorkerID, time.Now().Add(delay), err.Error()); err != nil {
+		log.Println("⚠️ Warning: could not schedule fanout_queue retry:", err)
+	}
+}
 
-		log.Printf("📦 Processing batch: %d followers (from %d to %d)\n", len(batch), i, end)
+func (w *FanoutWorker) ack(ctx context.Context, deliveryID string) {
+	if err := w.Queue.Ack(ctx, deliveryID); err != nil {
+		log.Println("⚠️ Warning: could not ack fanout message:", deliveryID, err)
+	}
+}
 
-		// ZADD
-		if err := w.FanoutRedis.PushPostToFollowers(ctx, fq.PostID.String(), batch); err != nil {
-			log.Println("❌ Error pushing batch to ZSET:", err)
-		} else {
-			log.Printf("✅ Pushed post %s to ZSET for %d followers\n", fq.PostID, len(batch))
+// پردازش یک رکورد FanoutQueue بر اساس نوع آن
+func (w *FanoutWorker) processFanout(ctx context.Context, fq *fanoutqueue.FanoutQueue) error {
+	if fq == nil || fq.UserID == uuid.Nil {
+		log.Println("❌ Invalid FanoutQueue record:", fq)
+		return errInvalidFanout
+	}
+
+	switch fq.Kind {
+	case fanoutqueue.KindPost, "":
+		return w.processPostFanout(ctx, fq)
+	case fanoutqueue.KindUnfollow:
+		return w.processUnfollow(ctx, fq)
+	case fanoutqueue.KindBackfill:
+		return w.processBackfill(ctx, fq)
+	case fanoutqueue.KindRetract:
+		return w.processRetract(ctx, fq)
+	default:
+		log.Println("❌ Unknown FanoutQueue kind:", fq.ID, fq.Kind)
+		return errInvalidFanout
+	}
+}
+
+// processPostFanout تحویل پست به تایم‌لاین followers نویسنده
+func (w *FanoutWorker) processPostFanout(ctx context.Context, fq *fanoutqueue.FanoutQueue) error {
+	if fq.PostID == nil || *fq.PostID == uuid.Nil {
+		log.Println("❌ Invalid FanoutQueue record:", fq)
+		return errInvalidFanout
+	}
+	postID := *fq.PostID
+
+	log.Printf("➡ Processing FanoutQueue: PostID=%s AuthorID=%s\n", postID, fq.UserID)
+
+	// برای نویسندگان celebrity push انجام نمی‌شود؛ تایم‌لاین followers پست‌های آن‌ها را موقع خواندن pull می‌کند
+	if w.CelebrityThreshold > 0 {
+		count, err := w.FollowerRepo.CountFollowers(ctx, fq.UserID.String())
+		if err != nil {
+			log.Println("❌ Error counting followers:", err)
+			return fmt.Errorf("count followers: %w", err)
+		}
+		if count > w.CelebrityThreshold {
+			log.Printf("⭐ Skipping push fanout for celebrity %s (%d followers)\n", fq.UserID, count)
+			if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+				log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+			}
+			return nil
 		}
+	}
 
-		if len(batch) <= 0 {
-			continue
+	// زمان ساخت پست امتیاز آن در تایم‌لاین است، نه زمان تحویل
+	post, err := w.PostRepo.FindByID(postID.String())
+	if err != nil {
+		log.Println("❌ Error loading post:", postID, err)
+		return fmt.Errorf("load post: %w", err)
+	}
+	if post.DeletedAt != nil {
+		// پست قبل از fanout حذف شده است؛ کار retract بقیه را پاک می‌کند
+		log.Printf("🗑️ Skipping fanout of deleted post %s\n", postID)
+		if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+			log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+		}
+		return nil
+	}
+
+	// برای پاسخ‌ها مخاطبان طبق ReplyFanout محدود می‌شوند
+	mutualWith, skip, err := w.replyAudience(post)
+	if err != nil {
+		return err
+	}
+	if skip {
+		log.Printf("💬 Skipping fanout of reply %s (REPLY_FANOUT=%s)\n", postID, w.ReplyFanout)
+		if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+			log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+		}
+		return nil
+	}
+
+	// repost به followers که پست اصلی را در تایم‌لاین دارند نمی‌رسد
+	original, skip, err := w.repostOriginal(post)
+	if err != nil {
+		return err
+	}
+	if skip {
+		log.Printf("🔁 Skipping fanout of repost %s, original was deleted\n", postID)
+		if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+			log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+		}
+		return nil
+	}
+
+	// رویدادی که بعد از هر batch برای followers آن منتشر می‌شود
+	event := w.timelineEvent(post)
+
+	// ادامه از آخرین follower تحویل‌شده در صورتی که قبلاً نیمه‌کاره مانده باشد
+	cursor := fq.LastFollowerID
+	if cursor != "" {
+		log.Printf("⏩ Resuming fanout %s after follower %s\n", fq.ID, cursor)
+	}
+
+	delivered := 0
+	for {
+		// گرفتن صفحه‌ی بعدی followers با keyset cursor
+		followers, err := w.FollowerRepo.GetFollowersPage(ctx, fq.UserID.String(), cursor, w.BatchSize)
+		if err != nil {
+			log.Println("❌ Error fetching followers:", err)
+			return fmt.Errorf("fetch followers: %w", err)
+		}
+		if len(followers) == 0 {
+			break
+		}
+
+		// تبدیل followers به []string
+		batch := make([]string, 0, len(followers))
+		for _, f := range followers {
+			batch = append(batch, f.FollowerID.String())
+		}
+
+		log.Printf("📦 Processing batch: %d followers (after %q)\n", len(batch), cursor)
+
+		// پاسخ فقط به followers که طرف دیگر گفتگو را هم دنبال می‌کنند؛ checkpoint همچنان روی کل batch است
+		recipients := batch
+		if mutualWith != "" {
+			recipients, err = w.FollowerRepo.FilterFollowing(ctx, batch, mutualWith)
+			if err != nil {
+				return fmt.Errorf("filter mutual followers: %w", err)
+			}
+		}
+
+		if original != nil {
+			recipients, err = w.withoutOriginal(ctx, original, recipients)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(recipients) > 0 {
+			// ZADD
+			// کلیدهای خراب فقط لاگ می‌شوند چون تکرار batch آن‌ها را درست نمی‌کند؛ تایم‌لاین MySQL همچنان ساخته می‌شود
+			var pushErr *fanoutPort.PushError
+			err := w.FanoutRedis.PushPostToFollowers(ctx, postID.String(), post.CreatedAt, recipients)
+			switch {
+			case errors.As(err, &pushErr):
+				for key, keyErr := range pushErr.FailedKeys {
+					log.Printf("⚠️ Warning: skipped %s for post %s: %v\n", key, postID, keyErr)
+				}
+			case err != nil:
+				log.Println("❌ Error pushing batch to ZSET:", err)
+				return fmt.Errorf("push batch to ZSET: %w", err)
+			default:
+				log.Printf("✅ Pushed post %s to ZSET for %d followers\n", postID, len(recipients))
+			}
+
+			// ساخت رکورد timeline به صورت batch
+			if err := addTimelines(ctx, w, postID, post.CreatedAt, recipients); err != nil {
+				return fmt.Errorf("add timeline batch: %w", err)
+			}
+
+			// اطلاع‌رسانی زنده؛ خطا فقط لاگ می‌شود چون پست در تایم‌لاین ثبت شده است
+			if err := w.publish(ctx, recipients, event); err != nil {
+				log.Println("⚠️ Warning: could not publish timeline event:", err)
+			}
 		}
 
-		// ساخت رکورد timeline به صورت batch
-		addTimelines(ctx, w, fq, batch)
+		// ذخیره‌ی checkpoint و تمدید lease تا بعد از کرش از همین نقطه ادامه دهیم
+		cursor = batch[len(batch)-1]
+		if err := w.FanoutRepo.SaveCheckpoint(ctx, fq.ID, w.WorkerID, cursor, w.LeaseTTL); err != nil {
+			log.Println("❌ Stopping fanout, could not save checkpoint:", fq.ID, err)
+			return err
+		}
+		delivered += len(recipients)
+
+		if len(followers) < w.BatchSize {
+			break
+		}
 	}
 
+	log.Printf("👥 Delivered post %s to %d followers of user %s\n", postID, delivered, fq.UserID)
+
 	// بروزرسانی وضعیت رکورد fanout_queue به done
-	if err := w.FanoutRepo.MarkDone(ctx, fq.ID); err != nil {
+	if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
 		log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
 	} else {
 		log.Printf("✅ FanoutQueue record marked as done: %s\n", fq.ID)
 	}
+	return nil
+}
+
+// replyAudience برای پاسخ‌ها تعیین می‌کند fanout انجام نشود (skip) یا فقط به followers که mutualWith
+// (نویسنده‌ی پست والد) را هم دنبال می‌کنند برسد؛ برای پست‌های معمولی و پاسخ به خود هر دو خالی هستند
+func (w *FanoutWorker) replyAudience(p *post.Post) (mutualWith string, skip bool, err error) {
+	if p.ReplyToID == nil {
+		return "", false, nil
+	}
+	switch w.ReplyFanout {
+	case ReplyFanoutAll:
+		return "", false, nil
+	case ReplyFanoutNone:
+		return "", true, nil
+	}
+
+	parent, err := w.PostRepo.FindByID(p.ReplyToID.String())
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		// گفتگو دیگر وجود ندارد
+		return "", true, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("load parent post: %w", err)
+	}
+	if parent.UserID == p.UserID {
+		return "", false, nil
+	}
+	return parent.UserID.String(), false, nil
+}
+
+// repostOriginal پست اصلی یک repost؛ برای پست‌های دیگر nil. اگر پست اصلی حذف شده باشد skip برمی‌گرداند
+func (w *FanoutWorker) repostOriginal(p *post.Post) (original *post.Post, skip bool, err error) {
+	if p.RepostOfID == nil {
+		return nil, false, nil
+	}
+	original, err = w.PostRepo.FindByID(p.RepostOfID.String())
+	if errors.Is(err, postPort.ErrPostNotFound) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("load original post: %w", err)
+	}
+	return original, original.DeletedAt != nil, nil
+}
+
+// withoutOriginal حذف کاربرانی از recipients که پست original یا repost دیگری از آن را در تایم‌لاین دارند
+// تا یک پست در صفحه‌های مختلف تایم‌لاین، /timeline/new و stream تکراری دیده نشود
+func (w *FanoutWorker) withoutOriginal(ctx context.Context, original *post.Post, recipients []string) ([]string, error) {
+	if len(recipients) == 0 {
+		return recipients, nil
+	}
+	have, err := w.TimelineRepo.FindUsersWithPost(ctx, original.ID.String(), recipients)
+	if err != nil {
+		return nil, fmt.Errorf("find timelines with original: %w", err)
+	}
+
+	// پست‌های celebrity ثبت نمی‌شوند و followers آن‌ها پست اصلی را موقع خواندن pull می‌کنند
+	if w.CelebrityThreshold > 0 {
+		count, err := w.FollowerRepo.CountFollowers(ctx, original.UserID.String())
+		if err != nil {
+			return nil, fmt.Errorf("count followers: %w", err)
+		}
+		if count > w.CelebrityThreshold {
+			pulled, err := w.FollowerRepo.FilterFollowing(ctx, recipients, original.UserID.String())
+			if err != nil {
+				return nil, fmt.Errorf("filter celebrity followers: %w", err)
+			}
+			have = append(have, pulled...)
+		}
+	}
+	if len(have) == 0 {
+		return recipients, nil
+	}
+
+	skip := make(map[string]bool, len(have))
+	for _, id := range have {
+		skip[id] = true
+	}
+	kept := make([]string, 0, len(recipients))
+	for _, id := range recipients {
+		if !skip[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
 }
 
-func addTimelines(ctx context.Context, w *FanoutWorker, fq *fanoutqueue.FanoutQueue, batch []string) {
+// processUnfollow حذف پست‌های نویسنده (UserID) از تایم‌لاین کاربری که او را آنفالو کرده (TargetUserID)؛
+// اگر کاربر در این فاصله دوباره follow کرده باشد کاری انجام نمی‌شود
+func (w *FanoutWorker) processUnfollow(ctx context.Context, fq *fanoutqueue.FanoutQueue) error {
+	if fq.TargetUserID == nil || *fq.TargetUserID == uuid.Nil {
+		log.Println("❌ Invalid FanoutQueue record:", fq)
+		return errInvalidFanout
+	}
+	userID := fq.TargetUserID.String()
+	authorID := fq.UserID.String()
+
+	log.Printf("➡ Processing unfollow cleanup: User=%s Author=%s\n", userID, authorID)
+
+	following, err := w.FollowerRepo.IsFollowing(ctx, userID, authorID)
+	if err != nil {
+		return fmt.Errorf("check follow status: %w", err)
+	}
+	if following {
+		log.Printf("↩️ User %s follows %s again, skipping cleanup\n", userID, authorID)
+		if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+			log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+		}
+		return nil
+	}
+
+	removed := 0
+	for {
+		postIDs, err := w.TimelineRepo.FindAuthorPostIDs(ctx, userID, authorID, w.BatchSize)
+		if err != nil {
+			return fmt.Errorf("find author posts: %w", err)
+		}
+		if len(postIDs) == 0 {
+			break
+		}
+
+		// اول ZSET و بعد MySQL، تا بعد از کرش همین پست‌ها دوباره پیدا و حذف شوند
+		if err := w.FanoutRedis.RemovePostsFromTimeline(ctx, userID, postIDs); err != nil {
+			return fmt.Errorf("remove posts from ZSET: %w", err)
+		}
+		if err := w.TimelineRepo.RemovePosts(ctx, userID, postIDs); err != nil {
+			return fmt.Errorf("remove timeline rows: %w", err)
+		}
+		removed += len(postIDs)
+
+		// تمدید lease؛ پیشرفت کار همان ردیف‌های حذف‌شده است و checkpoint لازم نیست
+		if err := w.FanoutRepo.SaveCheckpoint(ctx, fq.ID, w.WorkerID, "", w.LeaseTTL); err != nil {
+			log.Println("❌ Stopping unfollow cleanup, could not renew lease:", fq.ID, err)
+			return err
+		}
+
+		if len(postIDs) < w.BatchSize {
+			break
+		}
+	}
+
+	log.Printf("🧹 Removed %d posts of %s from timeline of %s\n", removed, authorID, userID)
+
+	if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+		log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+	}
+	return nil
+}
+
+// processRetract حذف پست PostID از تایم‌لاین همه‌ی کاربرانی که آن را دریافت کرده‌اند (fanout معکوس بعد از حذف پست)
+func (w *FanoutWorker) processRetract(ctx context.Context, fq *fanoutqueue.FanoutQueue) error {
+	if fq.PostID == nil || *fq.PostID == uuid.Nil {
+		log.Println("❌ Invalid FanoutQueue record:", fq)
+		return errInvalidFanout
+	}
+	postID := fq.PostID.String()
+
+	log.Printf("➡ Processing retract: PostID=%s AuthorID=%s\n", postID, fq.UserID)
+
+	removed := 0
+	for {
+		userIDs, err := w.TimelineRepo.FindPostUserIDs(ctx, postID, w.BatchSize)
+		if err != nil {
+			return fmt.Errorf("find timeline users: %w", err)
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+
+		// اول ZSET و بعد MySQL، تا بعد از کرش همین کاربران دوباره پیدا شوند
+		if err := w.FanoutRedis.RemovePostFromTimelines(ctx, postID, userIDs); err != nil {
+			return fmt.Errorf("remove post from ZSET: %w", err)
+		}
+		if err := w.TimelineRepo.RemovePostForUsers(ctx, postID, userIDs); err != nil {
+			return fmt.Errorf("remove timeline rows: %w", err)
+		}
+		removed += len(userIDs)
+
+		// تمدید lease؛ پیشرفت کار همان ردیف‌های حذف‌شده است و checkpoint لازم نیست
+		if err := w.FanoutRepo.SaveCheckpoint(ctx, fq.ID, w.WorkerID, "", w.LeaseTTL); err != nil {
+			log.Println("❌ Stopping retract, could not renew lease:", fq.ID, err)
+			return err
+		}
+
+		if len(userIDs) < w.BatchSize {
+			break
+		}
+	}
+
+	log.Printf("🗑️ Retracted post %s from %d timelines\n", postID, removed)
+
+	if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+		log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+	}
+	return nil
+}
+
+// processBackfill اضافه کردن آخرین BackfillLimit پست نویسنده (UserID) به تایم‌لاین follower جدید (TargetUserID)
+// با امتیاز زمان ساخت اصلی پست‌ها؛ اگر follow در این فاصله لغو شده باشد کاری انجام نمی‌شود
+func (w *FanoutWorker) processBackfill(ctx context.Context, fq *fanoutqueue.FanoutQueue) error {
+	if fq.TargetUserID == nil || *fq.TargetUserID == uuid.Nil {
+		log.Println("❌ Invalid FanoutQueue record:", fq)
+		return errInvalidFanout
+	}
+	userID := fq.TargetUserID.String()
+	authorID := fq.UserID.String()
+
+	log.Printf("➡ Processing follow backfill: User=%s Author=%s\n", userID, authorID)
+
+	following, err := w.FollowerRepo.IsFollowing(ctx, userID, authorID)
+	if err != nil {
+		return fmt.Errorf("check follow status: %w", err)
+	}
+	skip := !following || w.BackfillLimit <= 0
+
+	// پست‌های نویسندگان celebrity موقع خواندن pull می‌شوند
+	if !skip && w.CelebrityThreshold > 0 {
+		count, err := w.FollowerRepo.CountFollowers(ctx, authorID)
+		if err != nil {
+			return fmt.Errorf("count followers: %w", err)
+		}
+		skip = count > w.CelebrityThreshold
+	}
+	if skip {
+		log.Printf("⏭ Skipping backfill of %s into timeline of %s\n", authorID, userID)
+		if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+			log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+		}
+		return nil
+	}
+
+	posts, err := w.PostRepo.FindByUserID(authorID, w.BackfillLimit)
+	if err != nil {
+		return fmt.Errorf("load author posts: %w", err)
+	}
+
+	// پاسخ‌ها و repost ها با همان قواعد fanout زنده برای follower جدید فیلتر می‌شوند
+	posts, err = w.backfillAudience(ctx, userID, posts)
+	if err != nil {
+		return err
+	}
+
+	scores := make(map[string]time.Time, len(posts))
+	timelines := make([]*timelineEntity.Timeline, 0, len(posts))
+	for _, p := range posts {
+		scores[p.ID.String()] = p.CreatedAt
+		timelines = append(timelines, &timelineEntity.Timeline{
+			ID:        uuid.Must(uuid.NewV4()),
+			UserID:    *fq.TargetUserID,
+			PostID:    p.ID,
+			CreatedAt: p.CreatedAt,
+		})
+	}
+
+	if err := w.FanoutRedis.AddPostsToTimeline(ctx, userID, scores); err != nil {
+		return fmt.Errorf("add posts to ZSET: %w", err)
+	}
+	if len(timelines) > 0 {
+		if err := w.TimelineRepo.AddBatch(ctx, timelines); err != nil {
+			return fmt.Errorf("add timeline batch: %w", err)
+		}
+	}
+
+	log.Printf("📥 Backfilled %d posts of %s into timeline of %s\n", len(posts), authorID, userID)
+
+	if err := w.FanoutRepo.MarkDone(ctx, fq.ID, w.WorkerID); err != nil {
+		log.Println("⚠️ Warning: could not mark fanout_queue done:", err)
+	}
+	return nil
+}
+
+// backfillAudience حذف پاسخ‌هایی از posts که طبق replyAudience نباید به تایم‌لاین userID برسند
+// و repost هایی که پست اصلی آن‌ها در تایم‌لاین userID هست
+func (w *FanoutWorker) backfillAudience(ctx context.Context, userID string, posts []*post.Post) ([]*post.Post, error) {
+	following := make(map[string]bool) // نویسنده‌ی پست والد -> آیا userID او را دنبال می‌کند
+	kept := posts[:0]
+	for _, p := range posts {
+		mutualWith, skip, err := w.replyAudience(p)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+
+		original, skip, err := w.repostOriginal(p)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		if original != nil {
+			recipients, err := w.withoutOriginal(ctx, original, []string{userID})
+			if err != nil {
+				return nil, err
+			}
+			if len(recipients) == 0 {
+				continue
+			}
+		}
+		if mutualWith != "" {
+			ok, checked := following[mutualWith]
+			if !checked {
+				ok, err = w.FollowerRepo.IsFollowing(ctx, userID, mutualWith)
+				if err != nil {
+					return nil, fmt.Errorf("check follow status: %w", err)
+				}
+				following[mutualWith] = ok
+			}
+			if !ok {
+				continue
+			}
+		}
+		kept = append(kept, p)
+	}
+	return kept, nil
+}
+
+// publish انتشار رویداد برای followers در صورتی که ساخته شده باشد
+func (w *FanoutWorker) publish(ctx context.Context, userIDs []string, ev *eventsPort.Event) error {
+	if ev == nil {
+		return nil
+	}
+	return w.Events.Publish(ctx, userIDs, ev)
+}
+
+// timelineEvent ساخت رویداد تایم‌لاین برای پست به همراه اطلاعات نویسنده
+func (w *FanoutWorker) timelineEvent(p *post.Post) *eventsPort.Event {
+	lookup, err := postPort.LoadPostLookup(w.PostRepo, w.UserRepo, []*post.Post{p})
+	if err != nil {
+		log.Println("⚠️ Warning: could not load post author for event:", err)
+		lookup = nil
+	}
+
+	ev, err := postPort.NewTimelineEvent(lookup, p)
+	if err != nil {
+		log.Println("⚠️ Warning: could not build timeline event:", err)
+		return nil
+	}
+	return ev
+}
+
+func addTimelines(ctx context.Context, w *FanoutWorker, postID uuid.UUID, postCreatedAt time.Time, batch []string) error {
 	var timelines []*timelineEntity.Timeline
 	for _, fid := range batch {
 		timelines = append(timelines, &timelineEntity.Timeline{
 			ID:     uuid.Must(uuid.NewV4()),
 			UserID: uuid.FromStringOrNil(fid),
-			PostID: fq.PostID,
-			// CreatedAt: fq.CreatedAt,
+			PostID: postID,
+			// زمان ساخت پست تا ترتیب ردیف‌های MySQL با ZSET یکی باشد
+			CreatedAt: postCreatedAt,
 			// DeletedAt: nil,
 			// User:   user.User{},
 			// Post:   post.Post{},
@@ -146,16 +778,9 @@ func addTimelines(ctx context.Context, w *FanoutWorker, fq *fanoutqueue.FanoutQu
 
 	log.Printf("📝 Adding batch to timeline: %d records\n", len(timelines))
 	if err := w.TimelineRepo.AddBatch(ctx, timelines); err != nil {
-		log.Println("⚠️ Warning: could not add batch to timeline:", err)
-	} else {
-		log.Printf("✅ Added %d timeline records for post %s\n", len(timelines), fq.PostID)
-	}
-}
-
-// min helper function
-func min(a, b int) int {
-	if a < b {
-		return a
+		log.Println("❌ Error adding batch to timeline:", err)
+		return err
 	}
-	return b
+	log.Printf("✅ Added %d timeline records for post %s\n", len(timelines), postID)
+	return nil
 }
@@ -0,0 +1,69 @@
+package workers
+
+import (
+	"errors"
+	"testing"
+	"virast/internal/core/post"
+	postPort "virast/internal/ports/post"
+
+	"github.com/gofrs/uuid"
+)
+
+// fakePostRepository فقط FindByID برای پیدا کردن پست والد
+type fakePostRepository struct {
+	postPort.PostRepository
+	posts map[string]*post.Post
+	err   error
+}
+
+func (r *fakePostRepository) FindByID(id string) (*post.Post, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	p, ok := r.posts[id]
+	if !ok {
+		return nil, postPort.ErrPostNotFound
+	}
+	return p, nil
+}
+
+func TestReplyAudience(t *testing.T) {
+	alice := uuid.Must(uuid.NewV4())
+	bob := uuid.Must(uuid.NewV4())
+	parent := &post.Post{ID: uuid.Must(uuid.NewV4()), UserID: alice}
+	missingID := uuid.Must(uuid.NewV4())
+	repo := &fakePostRepository{posts: map[string]*post.Post{parent.ID.String(): parent}}
+	errDB := errors.New("db down")
+
+	tests := []struct {
+		name       string
+		mode       string
+		post       *post.Post
+		repoErr    error
+		wantMutual string
+		wantSkip   bool
+		wantErr    error
+	}{
+		{"not a reply", ReplyFanoutMutual, &post.Post{UserID: bob}, nil, "", false, nil},
+		{"all", ReplyFanoutAll, &post.Post{UserID: bob, ReplyToID: &parent.ID}, nil, "", false, nil},
+		{"none", ReplyFanoutNone, &post.Post{UserID: bob, ReplyToID: &parent.ID}, nil, "", true, nil},
+		{"mutual with parent author", ReplyFanoutMutual, &post.Post{UserID: bob, ReplyToID: &parent.ID}, nil, alice.String(), false, nil},
+		{"mutual self reply", ReplyFanoutMutual, &post.Post{UserID: alice, ReplyToID: &parent.ID}, nil, "", false, nil},
+		{"mutual parent gone", ReplyFanoutMutual, &post.Post{UserID: bob, ReplyToID: &missingID}, nil, "", true, nil},
+		{"mutual lookup error", ReplyFanoutMutual, &post.Post{UserID: bob, ReplyToID: &parent.ID}, errDB, "", false, errDB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo.err = tt.repoErr
+			w := &FanoutWorker{FanoutWorkerConfig: FanoutWorkerConfig{PostRepo: repo, ReplyFanout: tt.mode}}
+
+			mutual, skip, err := w.replyAudience(tt.post)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if mutual != tt.wantMutual || skip != tt.wantSkip {
+				t.Errorf("replyAudience = (%q, %v), want (%q, %v)", mutual, skip, tt.wantMutual, tt.wantSkip)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	fanoutPort "virast/internal/ports/fanoutqueue"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	relayInterval  = time.Second     // فاصله‌ی بررسی outbox
+	relayBatchSize = 100             // حداکثر رکورد در هر دور
+	relayGrace     = 5 * time.Second // رکوردهای تازه‌تر احتمالاً هنوز توسط PostService منتشر می‌شوند
+)
+
+// OutboxRelay انتشار رکوردهای fanout_queue که بعد از commit در stream منتشر نشده‌اند؛
+// انتشار قبل از ثبت published_at انجام می‌شود پس تحویل حداقل یک‌باره است و تکرار با ClaimByID خنثی می‌شود
+type OutboxRelay struct {
+	FanoutRepo fanoutPort.FanoutRepository
+	Queue      fanoutPort.FanoutQueue
+}
+
+func NewOutboxRelay(fanoutRepo fanoutPort.FanoutRepository, queue fanoutPort.FanoutQueue) *OutboxRelay {
+	return &OutboxRelay{
+		FanoutRepo: fanoutRepo,
+		Queue:      queue,
+	}
+}
+
+// Run اجرای relay تا لغو ctx
+func (r *OutboxRelay) Run(ctx context.Context) {
+	log.Println("🚀 OutboxRelay started")
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🛑 Outbox relay stopped")
+			return
+		case <-ticker.C:
+			r.relay(ctx)
+		}
+	}
+}
+
+func (r *OutboxRelay) relay(ctx context.Context) {
+	pending, err := r.FanoutRepo.GetUnpublished(ctx, time.Now().Add(-relayGrace), relayBatchSize)
+	if err != nil {
+		log.Println("❌ Error fetching unpublished fanout_queue records:", err)
+		return
+	}
+
+	published := make([]uuid.UUID, 0, len(pending))
+	for _, fq := range pending {
+		if err := r.Queue.Enqueue(ctx, fanoutPort.NewFanoutMessage(fq)); err != nil {
+			log.Println("❌ Error publishing fanout_queue record:", fq.ID, err)
+			break
+		}
+		published = append(published, fq.ID)
+	}
+
+	if len(published) == 0 {
+		return
+	}
+	if err := r.FanoutRepo.MarkPublished(ctx, published...); err != nil {
+		log.Println("⚠️ Warning: could not mark fanout_queue records published:", err)
+		return
+	}
+	log.Printf("📤 Relayed %d fanout_queue records to the stream\n", len(published))
+}